@@ -0,0 +1,181 @@
+package goog
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "strings"
+
+  l4g "code.google.com/p/log4go"
+)
+
+// batchRequest is the body of a request to the batch endpoint.
+type batchRequest struct {
+  Transaction bool             `json:"transaction"`
+  Operations  []batchOperation `json:"operations"`
+}
+
+// batchOperation is one of the operations of a batch request: c(reate),
+// u(pdate), d(elete), cmd or script.
+type batchOperation struct {
+  Type     string    `json:"type"`
+  Record   *Document `json:"record,omitempty"`
+  Language string    `json:"language,omitempty"`
+  Command  string    `json:"command,omitempty"`
+  Script   []string  `json:"script,omitempty"`
+}
+
+// Script runs SQL statements in order, in a single request and within a
+// transaction, and returns the records the last one answered with.
+func (db *DataBase) Script(statements ...string) ([]*Document, error) {
+  l4g.Trace("Inside Script")
+
+  return db.ScriptContext(context.Background(), statements...)
+}
+
+// ScriptContext is like Script, the request being canceled when ctx is done.
+func (db *DataBase) ScriptContext(ctx context.Context, statements ...string) ([]*Document, error) {
+  l4g.Trace("Inside ScriptContext")
+
+  return db.batch(ctx, []batchOperation{
+    {Type: "script", Language: SQL, Script: statements},
+  })
+}
+
+// batch sends operations to the batch endpoint within a transaction and
+// returns the records the last one answered with.
+func (db *DataBase) batch(ctx context.Context, operations []batchOperation) ([]*Document, error) {
+  var res result
+
+  body, err := json.Marshal(batchRequest{Transaction: true, Operations: operations})
+  if err != nil {
+    return nil, err
+  }
+
+  err = db.postRaw(ctx, &res, BATCH_URL+db.name, body)
+
+  return res.Result, err
+}
+
+// Tx accumulates operations to be sent together, as a single transaction, when
+// it is committed. Records created within the transaction get a temporary id
+// (#-1:-2, #-1:-3...) that later operations can link to; the server replaces
+// them with persistent ids on commit.
+type Tx struct {
+  db         *DataBase
+  operations []batchOperation
+  created    []*Document
+  done       bool
+}
+
+// Begin starts a transaction.
+func (db *DataBase) Begin() *Tx {
+  return &Tx{db: db}
+}
+
+// Create adds the creation of doc to the transaction and returns the
+// temporary id assigned to it, which is also set on doc.
+func (tx *Tx) Create(doc *Document) RID {
+  doc.RID = RID{ClusterID: -1, Position: -int64(len(tx.created) + 2)}
+  doc.Version = 0
+
+  tx.created = append(tx.created, doc)
+  tx.operations = append(tx.operations, batchOperation{Type: "c", Record: doc})
+
+  return doc.RID
+}
+
+// Update adds the update of doc to the transaction. Its version is checked on
+// commit as in UpdateDocument.
+func (tx *Tx) Update(doc *Document) {
+  tx.operations = append(tx.operations, batchOperation{Type: "u", Record: doc})
+}
+
+// Delete adds the removal of doc, at its current version, to the transaction.
+func (tx *Tx) Delete(doc *Document) {
+  tx.operations = append(tx.operations, batchOperation{
+    Type:   "d",
+    Record: &Document{RID: doc.RID, Version: doc.Version},
+  })
+}
+
+// Command adds a statement written in the given language to the transaction.
+func (tx *Tx) Command(language, text string) {
+  tx.operations = append(tx.operations, batchOperation{Type: "cmd", Language: language, Command: text})
+}
+
+// Script adds SQL statements to the transaction.
+func (tx *Tx) Script(statements ...string) {
+  tx.operations = append(tx.operations, batchOperation{Type: "script", Language: SQL, Script: statements})
+}
+
+// Commit sends every operation in a single request. On success the records
+// created get their persistent id and version, and the temporary ids they
+// had are returned mapped to the persistent ones. ErrMalformedResult is
+// returned, the transaction being committed nonetheless, when the records
+// read back don't match the ones created.
+func (tx *Tx) Commit() (map[RID]RID, error) {
+  l4g.Trace("Inside Tx.Commit")
+
+  return tx.CommitContext(context.Background())
+}
+
+// CommitContext is like Commit, the request being canceled when ctx is done.
+func (tx *Tx) CommitContext(ctx context.Context) (map[RID]RID, error) {
+  l4g.Trace("Inside Tx.CommitContext")
+
+  if tx.done {
+    return nil, ErrTxDone
+  }
+  tx.done = true
+
+  operations := tx.operations
+
+  // Reading the created records back as the last operation gives us their
+  // persistent ids, in the order they were created.
+  if len(tx.created) > 0 {
+    rids := make([]string, len(tx.created))
+    for i, doc := range tx.created {
+      rids[i] = doc.RID.String()
+    }
+    operations = append(operations, batchOperation{
+      Type:     "cmd",
+      Language: SQL,
+      Command:  "select from [" + strings.Join(rids, ", ") + "]",
+    })
+  }
+
+  docs, err := tx.db.batch(ctx, operations)
+  if err != nil {
+    return nil, err
+  }
+
+  // The transaction was committed by now, but without reading back every
+  // created record there is no telling which id each one got.
+  if len(tx.created) > 0 && len(docs) != len(tx.created) {
+    return nil, fmt.Errorf("%w: expecting %d created records, got %d.", ErrMalformedResult, len(tx.created), len(docs))
+  }
+
+  for i, doc := range tx.created {
+    if doc.Class != "" && docs[i].Class != doc.Class {
+      return nil, fmt.Errorf("%w: expecting a %s record for %s, got %s %s.", ErrMalformedResult, doc.Class, doc.RID, docs[i].Class, docs[i].RID)
+    }
+  }
+
+  assigned := make(map[RID]RID, len(tx.created))
+  for i, doc := range tx.created {
+    assigned[doc.RID] = docs[i].RID
+    doc.RID = docs[i].RID
+    doc.Version = docs[i].Version
+  }
+
+  return assigned, nil
+}
+
+// Rollback discards the operations of the transaction. Nothing was sent to
+// the server yet, so there is nothing to undo there.
+func (tx *Tx) Rollback() {
+  tx.done = true
+  tx.operations = nil
+  tx.created = nil
+}
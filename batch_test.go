@@ -0,0 +1,102 @@
+package goog
+
+import (
+  "encoding/json"
+  "errors"
+  "io/ioutil"
+  "net/http"
+  "testing"
+)
+
+func TestTx(t *testing.T) {
+  var path, answer string
+  var sent struct {
+    Transaction bool
+    Operations  []map[string]interface{}
+  }
+
+  ts, db := connectTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+    buf, _ := ioutil.ReadAll(r.Body)
+    path = r.URL.Path
+    json.Unmarshal(buf, &sent)
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    w.Write([]byte(answer))
+  })
+  defer ts.Close()
+
+  tx := db.Begin()
+
+  misa, beto := NewDocument("Person"), NewDocument("Person")
+  misa.Set("name", "Misa")
+  beto.Set("name", "Beto")
+
+  misaRID, betoRID := tx.Create(misa), tx.Create(beto)
+  if misaRID != (RID{-1, -2}) || betoRID != (RID{-1, -3}) || !misaRID.IsTemporary() {
+    t.Fatalf("Unexpected temporary ids %v %v.", misaRID, betoRID)
+  }
+
+  referrer := NewDocument("Referrer")
+  // Records created in the transaction are linked to, whether given by their
+  // temporary id or as they are.
+  referrer.Set("out", misa)
+  referrer.Set("in", betoRID)
+  tx.Create(referrer)
+
+  nor := &Document{RID: RID{11, 2}, Version: 4, Fields: map[string]interface{}{"name": "Nora"}}
+  tx.Update(nor)
+  tx.Delete(&Document{RID: RID{11, 3}, Version: 2})
+  tx.Command(SQL, "update Person set visits = visits + 1")
+
+  answer = `{"result": [
+    {"@type": "d", "@rid": "#11:7", "@version": 1, "@class": "Person", "name": "Misa"},
+    {"@type": "d", "@rid": "#11:8", "@version": 1, "@class": "Person", "name": "Beto"},
+    {"@type": "d", "@rid": "#12:3", "@version": 1, "@class": "Referrer"}
+  ]}`
+
+  assigned, err := tx.Commit()
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if path != "/batch/geneology" || !sent.Transaction || len(sent.Operations) != 7 {
+    t.Fatalf("Unexpected batch %s %v.", path, sent)
+  }
+
+  types := ""
+  for _, op := range sent.Operations {
+    types += op["type"].(string) + " "
+  }
+  if types != "c c c u d cmd cmd " {
+    t.Fatalf("Unexpected operations %q.", types)
+  }
+
+  link := sent.Operations[2]["record"].(map[string]interface{})
+  if link["out"] != "#-1:-2" || link["@fieldTypes"] != "in=x,out=x" {
+    t.Fatalf("Links to temporary ids were not sent as such: %v.", link)
+  }
+
+  if sent.Operations[6]["command"] != "select from [#-1:-2, #-1:-3, #-1:-4]" {
+    t.Fatalf("Unexpected read back %v.", sent.Operations[6])
+  }
+
+  if assigned[misaRID] != (RID{11, 7}) || assigned[betoRID] != (RID{11, 8}) || misa.RID != (RID{11, 7}) || referrer.RID != (RID{12, 3}) || misa.Version != 1 {
+    t.Fatalf("Unexpected assignments %v.", assigned)
+  }
+
+  if _, err = tx.Commit(); err != ErrTxDone {
+    t.Fatalf("Expecting ErrTxDone, got %v.", err)
+  }
+
+  for _, answer = range []string{
+    `{"result": []}`,
+    `{"result": [{"@type": "d", "@rid": "#12:4", "@version": 1, "@class": "Referrer"}]}`,
+  } {
+    tx = db.Begin()
+    nora := NewDocument("Person")
+    tx.Create(nora)
+
+    if _, err = tx.Commit(); !errors.Is(err, ErrMalformedResult) || nora.RID != (RID{-1, -2}) {
+      t.Fatalf("Expecting ErrMalformedResult for %s, got %v %v.", answer, err, nora.RID)
+    }
+  }
+}
@@ -0,0 +1,114 @@
+package goog
+
+// recordCache holds the records found in a result, expanded by a fetch plan
+// or returned at its top level, by their record id.
+type recordCache map[RID]*Document
+
+// resolveLinks replaces the links of docs pointing to records found anywhere
+// in docs by those records, so that a record expanded once is reached from
+// every link to it. Links to records that were not fetched are left as RID.
+func resolveLinks(docs ...*Document) {
+  cache := recordCache{}
+
+  for _, doc := range docs {
+    cache.collect(doc)
+  }
+
+  if len(cache) == 0 {
+    return
+  }
+
+  resolved := map[*Document]bool{}
+  for _, doc := range docs {
+    cache.resolve(doc, resolved)
+  }
+}
+
+// collect adds value to the cache if it is a saved record, and then every
+// record it embeds. A record is only kept once; a bare {"@rid": ...} gives
+// way to the record written in full.
+func (cache recordCache) collect(value interface{}) {
+  switch v := value.(type) {
+  case *Document:
+    if v == nil {
+      return
+    }
+    if v.RID != (RID{}) && v.RID.IsPersistent() && (len(v.Fields) > 0 || v.Class != "") {
+      if cached, ok := cache[v.RID]; !ok || len(cached.Fields) == 0 {
+        cache[v.RID] = v
+      }
+    }
+    for _, field := range v.Fields {
+      cache.collect(field)
+    }
+  case []interface{}:
+    for _, item := range v {
+      cache.collect(item)
+    }
+  case map[string]interface{}:
+    for _, entry := range v {
+      cache.collect(entry)
+    }
+  }
+}
+
+// resolve replaces the links of doc, and of the records it embeds, with the
+// cached records. resolved keeps cycles from being followed forever.
+func (cache recordCache) resolve(doc *Document, resolved map[*Document]bool) {
+  if doc == nil || resolved[doc] {
+    return
+  }
+  resolved[doc] = true
+
+  for name, field := range doc.Fields {
+    doc.Fields[name] = cache.link(field, resolved)
+  }
+}
+
+// link returns value with the record ids it holds replaced with the cached
+// records. A list of links becomes a []interface{} of *Document and RID as
+// soon as one of them was fetched.
+func (cache recordCache) link(value interface{}, resolved map[*Document]bool) interface{} {
+  switch v := value.(type) {
+  case RID:
+    if doc, ok := cache[v]; ok {
+      return doc
+    }
+  case []RID:
+    var links []interface{}
+    for i, rid := range v {
+      doc, ok := cache[rid]
+      if !ok {
+        continue
+      }
+      if links == nil {
+        links = make([]interface{}, len(v))
+        for j := range v {
+          links[j] = v[j]
+        }
+      }
+      links[i] = doc
+    }
+    if links != nil {
+      return links
+    }
+  case *Document:
+    if v == nil {
+      return value
+    }
+    if cached, ok := cache[v.RID]; ok && len(v.Fields) == 0 {
+      return cached
+    }
+    cache.resolve(v, resolved)
+  case []interface{}:
+    for i := range v {
+      v[i] = cache.link(v[i], resolved)
+    }
+  case map[string]interface{}:
+    for key := range v {
+      v[key] = cache.link(v[key], resolved)
+    }
+  }
+
+  return value
+}
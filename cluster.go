@@ -0,0 +1,209 @@
+package goog
+
+import (
+  "errors"
+  "io"
+  "net"
+  "strings"
+  "sync"
+  "syscall"
+  "time"
+
+  l4g "code.google.com/p/log4go"
+  "github.com/hiphoox/goog/rest"
+)
+
+// Routing is how a DataBase spreads its requests over the servers of a
+// cluster.
+type Routing int
+
+const (
+  // STICKY sends every request to the same server, moving to the next one
+  // only when it fails.
+  STICKY Routing = iota
+
+  // ROUND_ROBIN sends requests to each server in turn.
+  ROUND_ROBIN
+
+  // READ_REPLICA sends writes to the first server, the master, and reads to
+  // the other ones, the replicas, in turn.
+  READ_REPLICA
+)
+
+// DownTime is how long a server that could not be reached is avoided, unless
+// no other server is left.
+var DownTime = 30 * time.Second
+
+// node is one server of a cluster, with its own session.
+type node struct {
+  address   string
+  client    *rest.Client
+  session   bool
+  logins    int
+  downUntil time.Time
+
+  // auth serializes the logins with the server.
+  auth sync.Mutex
+}
+
+// cluster holds the servers a DataBase talks to. A single server is a cluster
+// of one.
+type cluster struct {
+  mu      sync.Mutex
+  nodes   []*node
+  routing Routing
+  current int
+  next    int
+}
+
+// newCluster creates a client for each server of config.
+func newCluster(config Config) (*cluster, error) {
+  c := &cluster{routing: config.Routing}
+
+  for _, address := range config.servers() {
+    client, err := config.newClient(address)
+    if err != nil {
+      return nil, err
+    }
+    c.nodes = append(c.nodes, &node{address: address, client: client})
+  }
+
+  if len(c.nodes) == 0 {
+    return nil, ErrNoServer
+  }
+
+  return c, nil
+}
+
+// candidates returns the servers to try a request on, in order. Servers
+// marked down come last.
+func (c *cluster) candidates(write bool) []*node {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  n := len(c.nodes)
+  order := make([]*node, 0, n)
+
+  switch {
+  case c.routing == ROUND_ROBIN:
+    start := c.next % n
+    c.next++
+    for i := 0; i < n; i++ {
+      order = append(order, c.nodes[(start+i)%n])
+    }
+  case c.routing == READ_REPLICA && !write && n > 1:
+    start := c.next % (n - 1)
+    c.next++
+    for i := 0; i < n-1; i++ {
+      order = append(order, c.nodes[1+(start+i)%(n-1)])
+    }
+    order = append(order, c.nodes[0])
+  case c.routing == READ_REPLICA:
+    order = append(order, c.nodes...)
+  default:
+    for i := 0; i < n; i++ {
+      order = append(order, c.nodes[(c.current+i)%n])
+    }
+  }
+
+  now := time.Now()
+  up := order[:0:0]
+  var down []*node
+  for _, candidate := range order {
+    if now.Before(candidate.downUntil) {
+      down = append(down, candidate)
+    } else {
+      up = append(up, candidate)
+    }
+  }
+
+  return append(up, down...)
+}
+
+// primary returns the server the last successful request went to.
+func (c *cluster) primary() *node {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  return c.nodes[c.current]
+}
+
+func (c *cluster) markUp(n *node) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  n.downUntil = time.Time{}
+  for i, candidate := range c.nodes {
+    if candidate == n {
+      c.current = i
+    }
+  }
+}
+
+func (c *cluster) markDown(n *node) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  l4g.Trace("Server %s is down", n.address)
+
+  n.session = false
+  n.downUntil = time.Now().Add(DownTime)
+}
+
+func (c *cluster) hasSession(n *node) bool {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  return n.session
+}
+
+func (c *cluster) setSession(n *node, session bool) {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  n.session = session
+  if session {
+    n.logins++
+  }
+}
+
+// loginsTo returns how many sessions were opened with the server so far.
+func (c *cluster) loginsTo(n *node) int {
+  c.mu.Lock()
+  defer c.mu.Unlock()
+
+  return n.logins
+}
+
+// unreachable tells whether err means the request never reached the server,
+// in which case any request can safely be sent to another one.
+func unreachable(err error) bool {
+  var opErr *net.OpError
+
+  return errors.Is(err, syscall.ECONNREFUSED) || (errors.As(err, &opErr) && opErr.Op == "dial")
+}
+
+// disconnected tells whether err means the connection with the server was
+// lost, maybe after the request was processed.
+func disconnected(err error) bool {
+  var netErr net.Error
+
+  return unreachable(err) ||
+    errors.Is(err, syscall.ECONNRESET) ||
+    errors.Is(err, io.EOF) ||
+    errors.Is(err, io.ErrUnexpectedEOF) ||
+    (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// splitServers splits a comma separated list of servers.
+func splitServers(servers string) []string {
+  var addresses []string
+
+  for _, address := range strings.Split(servers, ",") {
+    if address = strings.TrimSpace(address); address != "" {
+      addresses = append(addresses, address)
+    }
+  }
+
+  return addresses
+}
@@ -0,0 +1,203 @@
+package goog
+
+import (
+  "context"
+  "errors"
+  "net/http"
+  "net/http/httptest"
+  "strings"
+  "sync"
+  "sync/atomic"
+  "testing"
+)
+
+// testNode is a fake cluster member counting the requests it gets.
+type testNode struct {
+  *httptest.Server
+  mu       sync.Mutex
+  connects int
+  queries  int
+  commands int
+}
+
+func newTestNode(name string) *testNode {
+  n := new(testNode)
+  n.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    n.mu.Lock()
+    defer n.mu.Unlock()
+
+    switch {
+    case strings.HasPrefix(r.URL.Path, CONNECT_URL):
+      n.connects++
+      http.SetCookie(w, &http.Cookie{Name: SESSION_COOKIE, Value: name, Path: "/"})
+      w.WriteHeader(http.StatusNoContent)
+      return
+    case strings.HasPrefix(r.URL.Path, QUERY_URL):
+      n.queries++
+    case strings.HasPrefix(r.URL.Path, COMMAND_URL):
+      n.commands++
+    }
+
+    if cookie, err := r.Cookie(SESSION_COOKIE); err != nil || cookie.Value != name {
+      w.WriteHeader(http.StatusUnauthorized)
+      return
+    }
+
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    w.Write([]byte(`{"result": [{"@type": "d", "@rid": "#11:0", "@version": 1, "server": "` + name + `"}]}`))
+  }))
+  return n
+}
+
+func (n *testNode) address() string {
+  return strings.TrimPrefix(n.URL, HTTP_PREFIX)
+}
+
+func (n *testNode) counts() (int, int, int) {
+  n.mu.Lock()
+  defer n.mu.Unlock()
+  return n.connects, n.queries, n.commands
+}
+
+func queryServer(t *testing.T, db *DataBase) string {
+  records, err := db.Query("select from Person", 0, "")
+  if err != nil {
+    t.Fatal(err)
+  }
+  return records[0].GetString("server")
+}
+
+func TestFailover(t *testing.T) {
+  down := newTestNode("down")
+  down.Close()
+
+  first, second := newTestNode("first"), newTestNode("second")
+  defer first.Close()
+  defer second.Close()
+
+  db, err := Connect(down.address()+","+first.address()+","+second.address(), database_name, login, password)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if server := queryServer(t, &db); server != "first" || db.GetToken() != "first" {
+    t.Fatalf("Expecting requests on the first server up, got %q.", server)
+  }
+
+  // The server in use goes down: the session moves to the next one, logging
+  // in there.
+  first.Close()
+
+  if server := queryServer(t, &db); server != "second" || db.GetToken() != "second" {
+    t.Fatalf("Expecting a failover to the second server, got %q.", server)
+  }
+
+  if connects, queries, _ := second.counts(); connects != 1 || queries != 1 {
+    t.Fatalf("Unexpected %d connects and %d queries on the second server.", connects, queries)
+  }
+
+  // Servers down are skipped without trying them first.
+  if candidates := db.cluster.candidates(false); candidates[0].address != second.address() {
+    t.Fatalf("Expecting servers down last, got %s first.", candidates[0].address)
+  }
+
+  second.Close()
+
+  if _, err = db.Query("select from Person", 0, ""); err == nil {
+    t.Fatalf("Expecting an error with every server down.")
+  }
+}
+
+func TestRouting(t *testing.T) {
+  master, replica1, replica2 := newTestNode("master"), newTestNode("replica1"), newTestNode("replica2")
+  defer master.Close()
+  defer replica1.Close()
+  defer replica2.Close()
+
+  servers := master.address() + "," + replica1.address() + "," + replica2.address()
+
+  db, err := dial(context.Background(), Config{Server: servers, Database: database_name, Login: login, Password: password, Routing: ROUND_ROBIN})
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  var seen []string
+  for i := 0; i < 4; i++ {
+    seen = append(seen, queryServer(t, &db))
+  }
+
+  if seen[0] == seen[1] || seen[1] == seen[2] || seen[0] == seen[2] || seen[3] != seen[0] {
+    t.Fatalf("Unexpected round robin order %v.", seen)
+  }
+
+  db, err = dial(context.Background(), Config{Server: servers, Database: database_name, Login: login, Password: password, Routing: READ_REPLICA})
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  seen = nil
+  for i := 0; i < 3; i++ {
+    seen = append(seen, queryServer(t, &db))
+    if _, err = db.Command(SQL, "update Person set seen = true"); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  if strings.Join(seen, " ") != "replica1 replica2 replica1" {
+    t.Fatalf("Unexpected reads %v.", seen)
+  }
+
+  if _, _, commands := master.counts(); commands != 3 {
+    t.Fatalf("Expecting writes on the master, got %d.", commands)
+  }
+
+  // Without replicas, reads go to the master.
+  replica1.Close()
+  replica2.Close()
+
+  if server := queryServer(t, &db); server != "master" {
+    t.Fatalf("Expecting reads on the master, got %q.", server)
+  }
+}
+
+func TestFailoverWrites(t *testing.T) {
+  var posts int32
+
+  // A server dropping the connection once it received the request.
+  dropping := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    if strings.HasPrefix(r.URL.Path, CONNECT_URL) {
+      w.WriteHeader(http.StatusNoContent)
+      return
+    }
+    atomic.AddInt32(&posts, 1)
+    conn, _, _ := w.(http.Hijacker).Hijack()
+    conn.Close()
+  }))
+  defer dropping.Close()
+
+  other := newTestNode("other")
+  defer other.Close()
+
+  db, err := Connect(strings.TrimPrefix(dropping.URL, HTTP_PREFIX)+","+other.address(), database_name, login, password)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  // The command may have been applied: it must not be sent again elsewhere.
+  if _, err = db.Command(SQL, "create vertex Person"); err == nil || atomic.LoadInt32(&posts) != 1 {
+    t.Fatalf("Expecting the lost write to fail (%v, %d posts).", err, posts)
+  }
+
+  if _, _, commands := other.counts(); commands != 0 {
+    t.Fatalf("Write sent to another server.")
+  }
+
+  // Reads are safe to send again.
+  if server := queryServer(t, &db); server != "other" {
+    t.Fatalf("Expecting the read to fail over, got %q.", server)
+  }
+
+  if _, err = Connect("", database_name, login, password); !errors.Is(err, ErrNoServer) {
+    t.Fatalf("Expecting ErrNoServer, got %v.", err)
+  }
+}
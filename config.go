@@ -0,0 +1,83 @@
+package goog
+
+import (
+  "context"
+  "fmt"
+  "time"
+
+  l4g "code.google.com/p/log4go"
+  "github.com/hiphoox/goog/rest"
+)
+
+// Schemes a server can be reached with.
+const (
+  HTTP  = "http"
+  HTTPS = "https"
+)
+
+// Config describes how to reach a database. Options set the timeout,
+// transport, TLS and proxy settings of the underlying rest.Client.
+type Config struct {
+  Scheme   string  // HTTP (the default) or HTTPS
+  Server   string  // host:port, or several of them separated by commas
+  Routing  Routing // how requests are spread over several servers
+  Database string
+  Login    string
+  Password string
+
+  // Timeout limits the time each request may take, reading its response
+  // included, zero meaning no timeout. See QueryRows about streamed results.
+  Timeout time.Duration
+
+  // PoolSize is the MaxSessions of a Pool created with this Config, unless
+  // its PoolConfig says otherwise.
+  PoolSize int
+
+  // FetchPlan is used by queries and loads given no fetch plan.
+  FetchPlan FetchPlan
+
+  Options []rest.ClientOption
+}
+
+// ConnectWithConfig opens a session with the database described by config,
+// giving up when ctx is done.
+func ConnectWithConfig(ctx context.Context, config Config) (DataBase, error) {
+  l4g.Trace("Inside ConnectWithConfig")
+
+  return dial(ctx, config)
+}
+
+// servers returns the addresses of the servers of config.
+func (config Config) servers() []string {
+  return splitServers(config.Server)
+}
+
+// prefix returns the URL every request path to the given server is appended
+// to.
+func (config Config) prefix(address string) (string, error) {
+  switch config.Scheme {
+  case "", HTTP:
+    return HTTP_PREFIX + address, nil
+  case HTTPS:
+    return HTTPS_PREFIX + address, nil
+  }
+
+  return "", fmt.Errorf("%w %q.", ErrUnknownScheme, config.Scheme)
+}
+
+// newClient creates a client for the given server, with the options of
+// config followed by the given ones.
+func (config Config) newClient(address string, options ...rest.ClientOption) (*rest.Client, error) {
+  prefix, err := config.prefix(address)
+  if err != nil {
+    return nil, err
+  }
+
+  var all []rest.ClientOption
+  if config.Timeout > 0 {
+    all = append(all, rest.WithTimeout(config.Timeout))
+  }
+  all = append(append(all, config.Options...), options...)
+
+  return rest.New(prefix, all...)
+}
@@ -0,0 +1,94 @@
+package goog
+
+import (
+  "context"
+  "errors"
+  "net/http"
+  "net/http/httptest"
+  "strings"
+  "testing"
+
+  "github.com/hiphoox/goog/rest"
+)
+
+func TestConnectWithConfig(t *testing.T) {
+  ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    if strings.HasPrefix(r.URL.Path, CONNECT_URL) {
+      http.SetCookie(w, &http.Cookie{Name: SESSION_COOKIE, Value: "OS1", Path: "/"})
+      w.WriteHeader(http.StatusNoContent)
+      return
+    }
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    w.Write([]byte(`{"result": [{"@type": "d", "@rid": "#11:0", "@version": 1, "name": "Misa"}]}`))
+  }))
+  defer ts.Close()
+
+  config := Config{
+    Scheme:   HTTPS,
+    Server:   strings.TrimPrefix(ts.URL, HTTPS_PREFIX),
+    Database: database_name,
+    Login:    login,
+    Password: password,
+    Options:  []rest.ClientOption{rest.WithTransport(ts.Client().Transport)},
+  }
+
+  db, err := ConnectWithConfig(context.Background(), config)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if records, err := db.Query("select from Person", 0, ""); err != nil || len(records) != 1 {
+    t.Fatalf("Unexpected result %v (%v).", records, err)
+  }
+
+  // Without trusting the test certificate the connection fails.
+  config.Options = nil
+  if _, err = ConnectWithConfig(context.Background(), config); err == nil {
+    t.Fatalf("Expecting a certificate error.")
+  }
+
+  config.Scheme = "ftp"
+  if _, err = ConnectWithConfig(context.Background(), config); !errors.Is(err, ErrUnknownScheme) {
+    t.Fatalf("Expecting ErrUnknownScheme, got %v.", err)
+  }
+}
+
+func TestConnectWithTokenConfig(t *testing.T) {
+  var connects int
+
+  ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    if strings.HasPrefix(r.URL.Path, CONNECT_URL) {
+      connects++
+      http.SetCookie(w, &http.Cookie{Name: SESSION_COOKIE, Value: "OS2", Path: "/"})
+      w.WriteHeader(http.StatusNoContent)
+      return
+    }
+    if cookie, err := r.Cookie(SESSION_COOKIE); err != nil || cookie.Value != "OS2" {
+      w.WriteHeader(http.StatusUnauthorized)
+      return
+    }
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    w.Write([]byte(`{"result": [{"@type": "d", "@rid": "#11:0", "@version": 1, "name": "Misa"}]}`))
+  }))
+  defer ts.Close()
+
+  config := Config{
+    Scheme:   HTTPS,
+    Server:   strings.TrimPrefix(ts.URL, HTTPS_PREFIX),
+    Database: database_name,
+    Login:    login,
+    Password: password,
+    Options:  []rest.ClientOption{rest.WithTransport(ts.Client().Transport)},
+  }
+
+  // The server no longer knows OS1: the credentials of config open a new
+  // session.
+  db, err := ConnectWithTokenConfig(config, "OS1")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if records, err := db.Query("select from Person", 0, ""); err != nil || len(records) != 1 || connects != 1 || db.GetToken() != "OS2" {
+    t.Fatalf("Expecting a new session (%v, %d connects, token %q).", err, connects, db.GetToken())
+  }
+}
@@ -0,0 +1,222 @@
+package goog
+
+import (
+  "bytes"
+  "context"
+  "encoding/json"
+  "errors"
+  "net/url"
+  "strings"
+
+  l4g "code.google.com/p/log4go"
+)
+
+// UpdateRetries is the number of version conflicts UpdateWithRetry tolerates
+// before giving up.
+var UpdateRetries = 10
+
+// LoadDocument reads the record with the given id. fetchPlan (see FetchPlan)
+// may be left empty to use the one given by Config, or else the server's
+// default.
+func (db *DataBase) LoadDocument(rid RID, fetchPlan FetchPlan) (*Document, error) {
+  l4g.Trace("Inside LoadDocument")
+
+  return db.LoadDocumentContext(context.Background(), rid, fetchPlan)
+}
+
+// LoadDocumentContext is like LoadDocument, the request being canceled when
+// ctx is done.
+func (db *DataBase) LoadDocumentContext(ctx context.Context, rid RID, fetchPlan FetchPlan) (*Document, error) {
+  l4g.Trace("Inside LoadDocumentContext")
+  var doc Document
+
+  if fetchPlan == "" {
+    fetchPlan = db.fetchPlan
+  }
+
+  path := documentPath(db.name, rid)
+  if fetchPlan != "" {
+    path += "/" + url.PathEscape(fetchPlan.String())
+  }
+
+  if err := db.get(ctx, &doc, path, nil); err != nil {
+    return nil, err
+  }
+
+  resolveLinks(&doc)
+
+  return &doc, nil
+}
+
+// CreateDocument saves a new record and returns it as the server stored it.
+// The record id and version the server assigned are also copied into doc.
+func (db *DataBase) CreateDocument(doc *Document) (*Document, error) {
+  l4g.Trace("Inside CreateDocument")
+
+  return db.CreateDocumentContext(context.Background(), doc)
+}
+
+// CreateDocumentContext is like CreateDocument, the request being canceled
+// when ctx is done.
+func (db *DataBase) CreateDocumentContext(ctx context.Context, doc *Document) (*Document, error) {
+  l4g.Trace("Inside CreateDocumentContext")
+  var buf []byte
+
+  body, err := json.Marshal(doc)
+  if err != nil {
+    return nil, err
+  }
+
+  if err = db.postRaw(ctx, &buf, DOCUMENT_URL+db.name, body); err != nil {
+    return nil, err
+  }
+
+  return savedDocument(doc, buf)
+}
+
+// UpdateDocument replaces every field of a saved record with the fields of
+// doc. doc's @version must match the stored one, otherwise the update is
+// rejected with a *ConcurrentModificationError. The new version is copied into
+// doc.
+func (db *DataBase) UpdateDocument(doc *Document) (*Document, error) {
+  l4g.Trace("Inside UpdateDocument")
+
+  return db.updateDocument(context.Background(), doc, false)
+}
+
+// UpdateDocumentContext is like UpdateDocument, the request being canceled
+// when ctx is done.
+func (db *DataBase) UpdateDocumentContext(ctx context.Context, doc *Document) (*Document, error) {
+  l4g.Trace("Inside UpdateDocumentContext")
+
+  return db.updateDocument(ctx, doc, false)
+}
+
+// PatchDocument updates only the fields present in doc, leaving the other
+// fields of the stored record untouched. Versions are checked as in
+// UpdateDocument.
+func (db *DataBase) PatchDocument(doc *Document) (*Document, error) {
+  l4g.Trace("Inside PatchDocument")
+
+  return db.updateDocument(context.Background(), doc, true)
+}
+
+// PatchDocumentContext is like PatchDocument, the request being canceled when
+// ctx is done.
+func (db *DataBase) PatchDocumentContext(ctx context.Context, doc *Document) (*Document, error) {
+  l4g.Trace("Inside PatchDocumentContext")
+
+  return db.updateDocument(ctx, doc, true)
+}
+
+// UpdateWithRetry loads the record with the given id, applies update to it and
+// saves it, starting over from a fresh copy every time another writer
+// modified the record in between. It gives up after UpdateRetries conflicts,
+// returning the last *ConcurrentModificationError, or as soon as update
+// returns an error.
+func (db *DataBase) UpdateWithRetry(rid RID, update func(*Document) error) (*Document, error) {
+  l4g.Trace("Inside UpdateWithRetry")
+
+  return db.UpdateWithRetryContext(context.Background(), rid, update)
+}
+
+// UpdateWithRetryContext is like UpdateWithRetry, giving up when ctx is done.
+func (db *DataBase) UpdateWithRetryContext(ctx context.Context, rid RID, update func(*Document) error) (*Document, error) {
+  l4g.Trace("Inside UpdateWithRetryContext")
+  var err error
+
+  for attempt := 0; attempt <= UpdateRetries; attempt++ {
+    var doc, saved *Document
+
+    if doc, err = db.LoadDocumentContext(ctx, rid, ""); err != nil {
+      return nil, err
+    }
+
+    if err = update(doc); err != nil {
+      return nil, err
+    }
+
+    if saved, err = db.UpdateDocumentContext(ctx, doc); err == nil {
+      return saved, nil
+    }
+
+    if !errors.Is(err, ErrConcurrentModification) {
+      return nil, err
+    }
+
+    l4g.Trace("Record %s modified concurrently, retrying...", rid)
+  }
+
+  return nil, err
+}
+
+// updateDocument sends doc with PUT, or PATCH when patch is set.
+func (db *DataBase) updateDocument(ctx context.Context, doc *Document, patch bool) (*Document, error) {
+  var buf []byte
+
+  body, err := json.Marshal(doc)
+  if err != nil {
+    return nil, err
+  }
+
+  if patch {
+    err = db.patchRaw(ctx, &buf, documentPath(db.name, doc.RID), body)
+  } else {
+    err = db.putRaw(ctx, &buf, documentPath(db.name, doc.RID), body)
+  }
+
+  // We know better than the server's message what we based the update on.
+  var conflict *ConcurrentModificationError
+  if errors.As(err, &conflict) {
+    conflict.RID = doc.RID
+    conflict.Expected = doc.Version
+  }
+
+  if err != nil {
+    return nil, err
+  }
+
+  return savedDocument(doc, buf)
+}
+
+// DeleteDocument removes the record with the given id.
+func (db *DataBase) DeleteDocument(rid RID) error {
+  l4g.Trace("Inside DeleteDocument")
+
+  return db.DeleteDocumentContext(context.Background(), rid)
+}
+
+// DeleteDocumentContext is like DeleteDocument, the request being canceled
+// when ctx is done.
+func (db *DataBase) DeleteDocumentContext(ctx context.Context, rid RID) error {
+  l4g.Trace("Inside DeleteDocumentContext")
+
+  return db.delete(ctx, nil, documentPath(db.name, rid))
+}
+
+// documentPath builds /document/<db>/<cluster id>:<cluster position>.
+func documentPath(database_name string, rid RID) string {
+  return DOCUMENT_URL + database_name + "/" + strings.TrimPrefix(rid.String(), "#")
+}
+
+// savedDocument decodes the record the server answered a write with and
+// copies its id and version into doc. Older servers answer updates with a
+// plain text message instead, in which case the version is just increased.
+func savedDocument(doc *Document, buf []byte) (*Document, error) {
+  var saved Document
+
+  if !bytes.HasPrefix(bytes.TrimSpace(buf), []byte("{")) {
+    doc.Version++
+    saved = *doc
+    return &saved, nil
+  }
+
+  if err := json.Unmarshal(buf, &saved); err != nil {
+    return nil, err
+  }
+
+  doc.RID = saved.RID
+  doc.Version = saved.Version
+
+  return &saved, nil
+}
@@ -0,0 +1,142 @@
+package goog
+
+import (
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io/ioutil"
+  "net/http"
+  "testing"
+)
+
+func TestDocumentCRUD(t *testing.T) {
+  var method, path string
+  var sent map[string]interface{}
+
+  ts, db := connectTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+    method, path, sent = r.Method, r.URL.Path, nil
+    buf, _ := ioutil.ReadAll(r.Body)
+    json.Unmarshal(buf, &sent)
+
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    switch r.Method {
+    case "GET":
+      w.Write([]byte(`{"@type": "d", "@rid": "#11:4", "@version": 1, "@class": "Person", "name": "Misa"}`))
+    case "POST":
+      w.WriteHeader(http.StatusCreated)
+      w.Write([]byte(`{"@type": "d", "@rid": "#11:4", "@version": 1, "@class": "Person", "name": "Misa"}`))
+    case "PUT", "PATCH":
+      w.Write([]byte(`{"@type": "d", "@rid": "#11:4", "@version": 2, "@class": "Person", "name": "Misa Misa"}`))
+    case "DELETE":
+      w.WriteHeader(http.StatusNoContent)
+    }
+  })
+  defer ts.Close()
+
+  doc := NewDocument("Person")
+  doc.Set("name", "Misa")
+
+  saved, err := db.CreateDocument(doc)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if method != "POST" || path != "/document/geneology" || sent["@class"] != "Person" || sent["name"] != "Misa" {
+    t.Fatalf("Unexpected request %s %s %v.", method, path, sent)
+  }
+
+  if saved.RID != (RID{11, 4}) || doc.RID != (RID{11, 4}) || doc.Version != 1 {
+    t.Fatalf("Server assigned id was not returned: %v %v.", saved.RID, doc.RID)
+  }
+
+  loaded, err := db.LoadDocument(doc.RID, "*:1")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if method != "GET" || path != "/document/geneology/11:4/*:1" || loaded.GetString("name") != "Misa" {
+    t.Fatalf("Unexpected load %s %s %v.", method, path, loaded)
+  }
+
+  loaded.Set("name", "Misa Misa")
+  if _, err = db.UpdateDocument(loaded); err != nil {
+    t.Fatal(err)
+  }
+
+  if method != "PUT" || path != "/document/geneology/11:4" || sent["@version"] != float64(1) || loaded.Version != 2 {
+    t.Fatalf("Unexpected update %s %s %v.", method, path, sent)
+  }
+
+  patch := &Document{RID: loaded.RID, Version: loaded.Version, Fields: map[string]interface{}{"nick": "M"}}
+  if _, err = db.PatchDocument(patch); err != nil {
+    t.Fatal(err)
+  }
+
+  if method != "PATCH" || sent["nick"] != "M" || sent["name"] != nil {
+    t.Fatalf("Unexpected patch %s %v.", method, sent)
+  }
+
+  if err = db.DeleteDocument(doc.RID); err != nil {
+    t.Fatal(err)
+  }
+
+  if method != "DELETE" || path != "/document/geneology/11:4" {
+    t.Fatalf("Unexpected delete %s %s.", method, path)
+  }
+}
+
+func TestUpdateConflict(t *testing.T) {
+  version, loads, updates := 3, 0, 0
+
+  ts, db := connectTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+    var sent map[string]interface{}
+    buf, _ := ioutil.ReadAll(r.Body)
+    json.Unmarshal(buf, &sent)
+
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    switch r.Method {
+    case "GET":
+      loads++
+      fmt.Fprintf(w, `{"@type": "d", "@rid": "#11:4", "@version": %d, "visits": %d}`, version, version)
+      if loads == 1 {
+        // Somebody else saves the record right after our first read.
+        version++
+      }
+    case "PUT":
+      updates++
+      if int(sent["@version"].(float64)) != version {
+        w.WriteHeader(http.StatusConflict)
+        fmt.Fprintf(w, `{"errors": [{"code": 409, "reason": 409, "content": "com.orientechnologies.orient.core.exception.OConcurrentModificationException: Cannot UPDATE the record #11:4 because the version is not the latest. Probably you are updating an old record or it has been modified by another user (db=v%d your=v%d)"}]}`, version, int(sent["@version"].(float64)))
+        return
+      }
+      version++
+      fmt.Fprintf(w, `{"@type": "d", "@rid": "#11:4", "@version": %d, "visits": %v}`, version, sent["visits"])
+    }
+  })
+  defer ts.Close()
+
+  stale := &Document{RID: RID{11, 4}, Version: 2, Fields: map[string]interface{}{"visits": 1}}
+
+  _, err := db.UpdateDocument(stale)
+
+  var conflict *ConcurrentModificationError
+  if !errors.As(err, &conflict) || !errors.Is(err, ErrConcurrentModification) {
+    t.Fatalf("Expecting a *ConcurrentModificationError, got %v.", err)
+  }
+
+  if conflict.RID != (RID{11, 4}) || conflict.Expected != 2 || conflict.Actual != 3 {
+    t.Fatalf("Unexpected conflict %#v.", conflict)
+  }
+
+  saved, err := db.UpdateWithRetry(RID{11, 4}, func(doc *Document) error {
+    doc.Set("visits", doc.GetInt64("visits")+1)
+    return nil
+  })
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if loads != 2 || updates != 3 || saved.Version != 5 || saved.GetInt64("visits") != 5 {
+    t.Fatalf("Unexpected retry outcome: %d loads, %d updates, %v.", loads, updates, saved)
+  }
+}
@@ -0,0 +1,551 @@
+package goog
+
+import (
+  "bytes"
+  "encoding/json"
+  "fmt"
+  "math/big"
+  "sort"
+  "strings"
+  "time"
+)
+
+// Layouts OrientDB uses by default to write DATE and DATETIME fields.
+const (
+  dateLayout     = "2006-01-02"
+  dateTimeLayout = "2006-01-02 15:04:05"
+)
+
+// Document is a record as returned by OrientDB, with its metadata (@type,
+// @rid, @version, @class and @fieldTypes) kept apart from its fields.
+//
+// Field values are decoded following the @fieldTypes hints: longs become
+// int64, shorts int16, bytes int8, floats float32, doubles float64, decimals
+// json.Number, dates and datetimes time.Time and links RID or []RID. Numbers
+// without a hint become int or float64, objects carrying @type or @rid become
+// embedded *Document.
+//
+// Links to records expanded by a fetch plan are *Document, and lists of links
+// []interface{} holding *Document and RID. Query and LoadDocument also turn
+// into *Document the links to records found elsewhere in their result, as
+// OrientDB only writes a record in full the first time it meets it.
+//
+// A zero RID means the document has not been assigned one yet.
+type Document struct {
+  Type       string
+  RID        RID
+  Version    int
+  Class      string
+  FieldTypes map[string]string
+  Fields     map[string]interface{}
+}
+
+// NewDocument creates an empty document of the given class.
+func NewDocument(class string) *Document {
+  return &Document{
+    Type:       "d",
+    Class:      class,
+    FieldTypes: map[string]string{},
+    Fields:     map[string]interface{}{},
+  }
+}
+
+// newDocument builds a document from a record decoded with json.Decoder's
+// UseNumber, splitting metadata from fields.
+func newDocument(record map[string]interface{}) (*Document, error) {
+  var err error
+
+  doc := NewDocument("")
+
+  if s, ok := record["@fieldTypes"].(string); ok {
+    doc.FieldTypes = parseFieldTypes(s)
+  }
+
+  for key, value := range record {
+    switch key {
+    case "@type":
+      doc.Type, _ = value.(string)
+    case "@class":
+      doc.Class, _ = value.(string)
+    case "@fieldTypes":
+    case "@rid":
+      s, _ := value.(string)
+      if doc.RID, err = ParseRID(s); err != nil {
+        return nil, err
+      }
+    case "@version":
+      n, _ := value.(json.Number)
+      version, err := n.Int64()
+      if err != nil {
+        return nil, fmt.Errorf("Invalid @version %v: %s", value, err)
+      }
+      doc.Version = int(version)
+    default:
+      if doc.Fields[key], err = decodeField(value, doc.FieldTypes[key]); err != nil {
+        return nil, fmt.Errorf("Field %q: %s", key, err)
+      }
+    }
+  }
+
+  return doc, nil
+}
+
+// parseFieldTypes reads @fieldTypes, written as name=t,age=l.
+func parseFieldTypes(s string) map[string]string {
+  types := map[string]string{}
+
+  for _, pair := range strings.Split(s, ",") {
+    if i := strings.Index(pair, "="); i > 0 {
+      types[pair[:i]] = pair[i+1:]
+    }
+  }
+
+  return types
+}
+
+// decodeField converts a value decoded with UseNumber to the Go type matching
+// its @fieldTypes hint.
+func decodeField(value interface{}, hint string) (interface{}, error) {
+  switch v := value.(type) {
+  case json.Number:
+    return decodeNumber(v, hint)
+  case string:
+    switch hint {
+    case "t":
+      return time.ParseInLocation(dateTimeLayout, v, time.Local)
+    case "a":
+      return time.ParseInLocation(dateLayout, v, time.Local)
+    case "x":
+      return ParseRID(v)
+    }
+    return v, nil
+  case []interface{}:
+    switch hint {
+    case "z", "n", "g":
+      return decodeLinks(v)
+    }
+    list := make([]interface{}, len(v))
+    for i := range v {
+      var err error
+      if list[i], err = decodeField(v[i], ""); err != nil {
+        return nil, err
+      }
+    }
+    return list, nil
+  case map[string]interface{}:
+    _, isDocument := v["@type"]
+    if _, hasRID := v["@rid"]; isDocument || hasRID {
+      return newDocument(v)
+    }
+    embedded := make(map[string]interface{}, len(v))
+    for key := range v {
+      var err error
+      if embedded[key], err = decodeField(v[key], ""); err != nil {
+        return nil, err
+      }
+    }
+    return embedded, nil
+  }
+
+  return value, nil
+}
+
+func decodeNumber(n json.Number, hint string) (interface{}, error) {
+  switch hint {
+  case "l":
+    return n.Int64()
+  case "s":
+    i, err := n.Int64()
+    return int16(i), err
+  case "b":
+    i, err := n.Int64()
+    return int8(i), err
+  case "f":
+    f, err := n.Float64()
+    return float32(f), err
+  case "d":
+    return n.Float64()
+  case "c":
+    return n, nil
+  case "t", "a":
+    millis, err := n.Int64()
+    return time.Unix(0, millis*int64(time.Millisecond)), err
+  }
+
+  if i, err := n.Int64(); err == nil {
+    return int(i), nil
+  }
+
+  return n.Float64()
+}
+
+// decodeLinks reads a list of links, given either as record ids or as records
+// expanded by a fetch plan. It returns a []RID unless some of them were
+// expanded, in which case they are kept as *Document in a []interface{}.
+func decodeLinks(values []interface{}) (interface{}, error) {
+  links := make([]RID, 0, len(values))
+  var expanded []interface{}
+
+  for i, value := range values {
+    var link interface{}
+
+    switch v := value.(type) {
+    case string:
+      rid, err := ParseRID(v)
+      if err != nil {
+        return nil, err
+      }
+      link = rid
+    case map[string]interface{}:
+      doc, err := newDocument(v)
+      if err != nil {
+        return nil, err
+      }
+      link = doc
+    default:
+      return nil, fmt.Errorf("%w %v.", ErrInvalidRID, value)
+    }
+
+    if rid, ok := link.(RID); ok && expanded == nil {
+      links = append(links, rid)
+      continue
+    }
+
+    if expanded == nil {
+      expanded = make([]interface{}, len(values))
+      for j := 0; j < i; j++ {
+        expanded[j] = links[j]
+      }
+    }
+    expanded[i] = link
+  }
+
+  if expanded != nil {
+    return expanded, nil
+  }
+
+  return links, nil
+}
+
+// UnmarshalJSON decodes a record keeping numbers exact, so that longs and
+// decimals don't go through float64.
+func (doc *Document) UnmarshalJSON(data []byte) error {
+  var record map[string]interface{}
+
+  decoder := json.NewDecoder(bytes.NewReader(data))
+  decoder.UseNumber()
+
+  if err := decoder.Decode(&record); err != nil {
+    return err
+  }
+
+  decoded, err := newDocument(record)
+  if err != nil {
+    return err
+  }
+
+  *doc = *decoded
+
+  return nil
+}
+
+// MarshalJSON encodes the document the way OrientDB expects it, adding
+// @fieldTypes hints for time.Time, int64, RID and []RID fields. Saved records,
+// such as the ones expanded by a fetch plan, and records created in a Tx are
+// written as links; other documents are embedded.
+func (doc *Document) MarshalJSON() ([]byte, error) {
+  record := make(map[string]interface{}, len(doc.Fields)+5)
+  types := make(map[string]string, len(doc.FieldTypes))
+
+  for name, hint := range doc.FieldTypes {
+    types[name] = hint
+  }
+
+  for name, value := range doc.Fields {
+    switch v := value.(type) {
+    case time.Time:
+      if types[name] == "a" {
+        record[name] = v.Format(dateLayout)
+        continue
+      }
+      types[name] = "t"
+      record[name] = v.Format(dateTimeLayout)
+      continue
+    case int64:
+      if types[name] == "" {
+        types[name] = "l"
+      }
+    case RID:
+      types[name] = "x"
+    case []RID:
+      if types[name] == "" {
+        types[name] = "z"
+      }
+    case *Document:
+      if isLinked(v) {
+        types[name] = "x"
+        record[name] = v.RID
+        continue
+      }
+    case []interface{}:
+      if links, ok := linkedRIDs(v); ok {
+        if types[name] == "" {
+          types[name] = "z"
+        }
+        record[name] = links
+        continue
+      }
+    }
+    record[name] = value
+  }
+
+  record["@type"] = doc.Type
+  if doc.Type == "" {
+    record["@type"] = "d"
+  }
+
+  if doc.RID != (RID{}) {
+    record["@rid"] = doc.RID
+    record["@version"] = doc.Version
+  }
+
+  if doc.Class != "" {
+    record["@class"] = doc.Class
+  }
+
+  if len(types) > 0 {
+    pairs := make([]string, 0, len(types))
+    for name, hint := range types {
+      if _, ok := doc.Fields[name]; ok {
+        pairs = append(pairs, name+"="+hint)
+      }
+    }
+    sort.Strings(pairs)
+    if len(pairs) > 0 {
+      record["@fieldTypes"] = strings.Join(pairs, ",")
+    }
+  }
+
+  return json.Marshal(record)
+}
+
+// isLinked reports whether doc is a saved record or one created in a Tx
+// (#-1:-2, #-1:-3...), linked to rather than embedded. #-1:-1 is the id
+// OrientDB gives embedded documents.
+func isLinked(doc *Document) bool {
+  if doc == nil || doc.RID == (RID{}) {
+    return false
+  }
+
+  return doc.RID.IsPersistent() || (doc.RID.ClusterID == -1 && doc.RID.Position < -1)
+}
+
+// linkedRIDs returns the record ids of a list of links holding saved records
+// expanded by a fetch plan. ok is false when the list holds anything else.
+func linkedRIDs(items []interface{}) (links []RID, ok bool) {
+  links = make([]RID, len(items))
+
+  for i, item := range items {
+    switch v := item.(type) {
+    case RID:
+      links[i] = v
+    case *Document:
+      if !isLinked(v) {
+        return nil, false
+      }
+      links[i] = v.RID
+      ok = true
+    default:
+      return nil, false
+    }
+  }
+
+  return links, ok
+}
+
+// Get returns the value of the named field or nil when there is none.
+func (doc *Document) Get(name string) interface{} {
+  return doc.Fields[name]
+}
+
+// Set sets the value of the named field.
+func (doc *Document) Set(name string, value interface{}) {
+  if doc.Fields == nil {
+    doc.Fields = map[string]interface{}{}
+  }
+  doc.Fields[name] = value
+}
+
+// GetString returns the named field as a string, or "" when it is not one.
+func (doc *Document) GetString(name string) string {
+  switch v := doc.Fields[name].(type) {
+  case string:
+    return v
+  case json.Number:
+    return v.String()
+  case RID:
+    return v.String()
+  }
+
+  return ""
+}
+
+// GetInt64 returns the named field as an int64, or 0 when it is not a number.
+func (doc *Document) GetInt64(name string) int64 {
+  switch v := doc.Fields[name].(type) {
+  case int:
+    return int64(v)
+  case int8:
+    return int64(v)
+  case int16:
+    return int64(v)
+  case int32:
+    return int64(v)
+  case int64:
+    return v
+  case float32:
+    return int64(v)
+  case float64:
+    return int64(v)
+  case json.Number:
+    if i, err := v.Int64(); err == nil {
+      return i
+    }
+    f, _ := v.Float64()
+    return int64(f)
+  }
+
+  return 0
+}
+
+// GetFloat64 returns the named field as a float64, or 0 when it is not a
+// number.
+func (doc *Document) GetFloat64(name string) float64 {
+  switch v := doc.Fields[name].(type) {
+  case float32:
+    return float64(v)
+  case float64:
+    return v
+  case json.Number:
+    f, _ := v.Float64()
+    return f
+  }
+
+  return float64(doc.GetInt64(name))
+}
+
+// GetDecimal returns the named field as an exact decimal, or nil when it is
+// not a number.
+func (doc *Document) GetDecimal(name string) *big.Rat {
+  var s string
+
+  switch v := doc.Fields[name].(type) {
+  case json.Number:
+    s = v.String()
+  case int, int8, int16, int32, int64, float32, float64:
+    s = fmt.Sprint(v)
+  default:
+    return nil
+  }
+
+  if r, ok := new(big.Rat).SetString(s); ok {
+    return r
+  }
+
+  return nil
+}
+
+// GetBool returns the named field as a bool, or false when it is not one.
+func (doc *Document) GetBool(name string) bool {
+  b, _ := doc.Fields[name].(bool)
+  return b
+}
+
+// GetTime returns the named date or datetime field, or the zero time when it
+// is not one.
+func (doc *Document) GetTime(name string) time.Time {
+  t, _ := doc.Fields[name].(time.Time)
+  return t
+}
+
+// GetLink returns the record id stored in the named field, or the zero RID
+// when there is none.
+func (doc *Document) GetLink(name string) RID {
+  if links := doc.GetLinks(name); len(links) == 1 {
+    return links[0]
+  }
+
+  return RID{}
+}
+
+// GetLinks returns the record ids stored in the named field, whether they
+// were received as record ids or as records expanded by a fetch plan.
+func (doc *Document) GetLinks(name string) []RID {
+  switch v := doc.Fields[name].(type) {
+  case RID:
+    return []RID{v}
+  case []RID:
+    return v
+  case string:
+    if rid, err := ParseRID(v); err == nil {
+      return []RID{rid}
+    }
+  case *Document:
+    return []RID{v.RID}
+  case []interface{}:
+    links := make([]RID, 0, len(v))
+    for _, value := range v {
+      switch link := value.(type) {
+      case RID:
+        links = append(links, link)
+      case *Document:
+        links = append(links, link.RID)
+      case string:
+        rid, err := ParseRID(link)
+        if err != nil {
+          return nil
+        }
+        links = append(links, rid)
+      default:
+        return nil
+      }
+    }
+    return links
+  }
+
+  return nil
+}
+
+// GetDocuments returns the records the named link field points to, as
+// expanded by a fetch plan. Links to records that were not fetched are left
+// out.
+func (doc *Document) GetDocuments(name string) []*Document {
+  switch v := doc.Fields[name].(type) {
+  case *Document:
+    return []*Document{v}
+  case []interface{}:
+    docs := make([]*Document, 0, len(v))
+    for _, value := range v {
+      if linked, ok := value.(*Document); ok {
+        docs = append(docs, linked)
+      }
+    }
+    return docs
+  }
+
+  return nil
+}
+
+// GetEmbedded returns the named field as a document, either an embedded
+// record or a linked one expanded by a fetch plan. It returns nil when the
+// field holds neither.
+func (doc *Document) GetEmbedded(name string) *Document {
+  switch v := doc.Fields[name].(type) {
+  case *Document:
+    return v
+  case map[string]interface{}:
+    return &Document{Type: "d", FieldTypes: map[string]string{}, Fields: v}
+  }
+
+  return nil
+}
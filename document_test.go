@@ -0,0 +1,81 @@
+package goog
+
+import (
+  "encoding/json"
+  "testing"
+  "time"
+)
+
+func TestDocumentUnmarshal(t *testing.T) {
+  var doc Document
+
+  err := json.Unmarshal([]byte(`{
+    "@type": "d", "@rid": "#11:1", "@version": 3, "@class": "Person",
+    "@fieldTypes": "born=t,visits=l,salary=c,best=x,friends=z",
+    "name": "Nor", "age": 31, "height": 1.62,
+    "born": "1983-05-24 10:30:00", "visits": 9007199254740993, "salary": 1234.56,
+    "best": "#11:2", "friends": ["#11:2", "#11:3"],
+    "out": ["#12:0"],
+    "address": {"@type": "d", "city": "Puebla"}
+  }`), &doc)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if doc.Type != "d" || doc.RID != (RID{11, 1}) || doc.Version != 3 || doc.Class != "Person" {
+    t.Fatalf("Unexpected metadata %#v.", doc)
+  }
+
+  if _, ok := doc.Fields["@class"]; ok {
+    t.Fatalf("Metadata should not be part of the fields.")
+  }
+
+  if doc.GetString("name") != "Nor" || doc.Get("age") != 31 || doc.GetFloat64("height") != 1.62 {
+    t.Fatalf("Unexpected fields %v.", doc.Fields)
+  }
+
+  if doc.GetInt64("visits") != 9007199254740993 {
+    t.Fatalf("Long lost precision: %v.", doc.Get("visits"))
+  }
+
+  if doc.GetDecimal("salary").FloatString(2) != "1234.56" {
+    t.Fatalf("Unexpected decimal %v.", doc.Get("salary"))
+  }
+
+  if born := doc.GetTime("born"); born.Year() != 1983 || born.Hour() != 10 {
+    t.Fatalf("Unexpected datetime %v.", doc.Get("born"))
+  }
+
+  if doc.GetLink("best") != (RID{11, 2}) || len(doc.GetLinks("friends")) != 2 || doc.GetLinks("out")[0] != (RID{12, 0}) {
+    t.Fatalf("Unexpected links %v.", doc.Fields)
+  }
+
+  if address := doc.GetEmbedded("address"); address == nil || address.GetString("city") != "Puebla" {
+    t.Fatalf("Unexpected embedded document %v.", doc.Get("address"))
+  }
+}
+
+func TestDocumentMarshal(t *testing.T) {
+  doc := NewDocument("Person")
+  doc.Set("name", "Misa")
+  doc.Set("visits", int64(12))
+  doc.Set("born", time.Date(1980, 1, 2, 3, 4, 5, 0, time.Local))
+
+  buf, err := json.Marshal(doc)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if string(buf) != `{"@class":"Person","@fieldTypes":"born=t,visits=l","@type":"d","born":"1980-01-02 03:04:05","name":"Misa","visits":12}` {
+    t.Fatalf("Unexpected encoded document %s.", buf)
+  }
+
+  var decoded Document
+  if err = json.Unmarshal(buf, &decoded); err != nil {
+    t.Fatal(err)
+  }
+
+  if decoded.Get("visits") != int64(12) || !decoded.GetTime("born").Equal(doc.GetTime("born")) {
+    t.Fatalf("Document did not round-trip: %v.", decoded.Fields)
+  }
+}
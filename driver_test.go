@@ -0,0 +1,172 @@
+package goog
+
+import (
+  "database/sql"
+  "database/sql/driver"
+  "encoding/json"
+  "io/ioutil"
+  "net/http"
+  "strings"
+  "testing"
+)
+
+func openTestDriver(t *testing.T, handler http.HandlerFunc) (*sql.DB, func()) {
+  ts, _ := connectTestServer(t, handler)
+
+  db, err := sql.Open(DRIVER_NAME, "orientdb://"+login+":"+password+"@"+strings.TrimPrefix(ts.URL, HTTP_PREFIX)+"/"+database_name)
+  if err != nil {
+    ts.Close()
+    t.Fatal(err)
+  }
+
+  return db, func() {
+    db.Close()
+    ts.Close()
+  }
+}
+
+func TestDriverQuery(t *testing.T) {
+  var method, path, body string
+
+  db, done := openTestDriver(t, func(w http.ResponseWriter, r *http.Request) {
+    buf, _ := ioutil.ReadAll(r.Body)
+    method, path, body = r.Method, r.URL.Path, string(buf)
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    w.Write([]byte(`{"result": [
+      {"@type": "d", "@rid": "#11:0", "@version": 1, "@class": "Person", "name": "Misa", "born": 1977},
+      {"@type": "d", "@rid": "#11:1", "@version": 1, "@class": "Person", "name": "Beto", "out_Knows": ["#12:0"]}
+    ]}`))
+  })
+  defer done()
+
+  rows, err := db.Query("select from Person")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer rows.Close()
+
+  if method != "GET" || !strings.HasPrefix(path, QUERY_URL+database_name+"/sql/") {
+    t.Fatalf("Expecting a query request, got %s %s.", method, path)
+  }
+
+  columns, _ := rows.Columns()
+  if strings.Join(columns, ",") != "@rid,@class,born,name,out_Knows" {
+    t.Fatalf("Unexpected columns %v.", columns)
+  }
+
+  var rid, class, name string
+  var born sql.NullInt64
+  var knows sql.NullString
+
+  var names []string
+  for rows.Next() {
+    if err = rows.Scan(&rid, &class, &born, &name, &knows); err != nil {
+      t.Fatal(err)
+    }
+    names = append(names, rid+" "+class+" "+name)
+  }
+
+  if strings.Join(names, ", ") != "#11:0 Person Misa, #11:1 Person Beto" || born.Valid || knows.String != `["#12:0"]` {
+    t.Fatalf("Unexpected rows %v (%v, %v).", names, born, knows)
+  }
+
+  // Arguments go through the command endpoint.
+  if err = db.QueryRow("select from Person where name = ?", "Misa").Scan(&rid, &class, &born, &name, &knows); err != nil {
+    t.Fatal(err)
+  }
+
+  var req commandRequest
+  json.Unmarshal([]byte(body), &req)
+
+  if method != "POST" || path != COMMAND_URL+database_name+"/sql" || req.Command != "select from Person where name = ?" {
+    t.Fatalf("Expecting a command request, got %s %s %s.", method, path, body)
+  }
+
+  if params, ok := req.Parameters.([]interface{}); !ok || len(params) != 1 || params[0] != "Misa" {
+    t.Fatalf("Unexpected parameters %v.", req.Parameters)
+  }
+}
+
+func TestDriverExec(t *testing.T) {
+  var paths, bodies []string
+
+  db, done := openTestDriver(t, func(w http.ResponseWriter, r *http.Request) {
+    buf, _ := ioutil.ReadAll(r.Body)
+    paths, bodies = append(paths, r.URL.Path), append(bodies, string(buf))
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    w.Write([]byte(`{"result": [{"@type": "d", "@version": 0, "value": 3}]}`))
+  })
+  defer done()
+
+  res, err := db.Exec("update Person set seen = true where born < ?", 1980)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if n, err := res.RowsAffected(); err != nil || n != 3 {
+    t.Fatalf("Unexpected rows affected %d (%v).", n, err)
+  }
+
+  tx, err := db.Begin()
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  tx.Exec("create vertex Person set name = ?, born = ?", "O'Hara \"Bo\"", 1980)
+  tx.Exec("create edge Knows from ? to ?", RID{11, 0}, RID{11, 1})
+
+  if len(paths) != 1 {
+    t.Fatalf("Statements should wait for the commit.")
+  }
+
+  if err = tx.Commit(); err != nil {
+    t.Fatal(err)
+  }
+
+  var batch batchRequest
+  json.Unmarshal([]byte(bodies[1]), &batch)
+
+  if paths[1] != BATCH_URL+database_name || !batch.Transaction || len(batch.Operations) != 2 {
+    t.Fatalf("Unexpected batch %s %s.", paths[1], bodies[1])
+  }
+
+  if batch.Operations[0].Command != `create vertex Person set name = "O'Hara \"Bo\"", born = 1980` ||
+    batch.Operations[1].Command != "create edge Knows from #11:0 to #11:1" {
+    t.Fatalf("Unexpected commands %+v.", batch.Operations)
+  }
+
+  tx, _ = db.Begin()
+  tx.Exec("delete vertex Person")
+  if err = tx.Rollback(); err != nil || len(paths) != 2 {
+    t.Fatalf("Rolled back statements should not be sent.")
+  }
+}
+
+func TestBindArgs(t *testing.T) {
+  text, err := bindArgs(`select from Person where name = '?' and nick = "\"?" and born = ?`, namedValues([]driver.Value{1977}))
+  if err != nil || text != `select from Person where name = '?' and nick = "\"?" and born = 1977` {
+    t.Fatalf("Unexpected statement %q (%v).", text, err)
+  }
+
+  if _, err = bindArgs("select from Person where name = ?", nil); err != ErrMissingArgument {
+    t.Fatalf("Expecting ErrMissingArgument, got %v.", err)
+  }
+
+  text, err = bindArgs("update Person set name = ? where @rid = #11:0", namedValues([]driver.Value{"Tom & <Jerry>"}))
+  if err != nil || text != `update Person set name = "Tom & <Jerry>" where @rid = #11:0` {
+    t.Fatalf("Unexpected statement %q (%v).", text, err)
+  }
+
+  named := []driver.NamedValue{{Name: "name", Ordinal: 1, Value: "Misa"}, {Name: "born", Ordinal: 2, Value: int64(1977)}}
+
+  text, err = bindArgs("update #11:0 set name = :name, nick = ':name', born = :born", named)
+  if err != nil || text != `update #11:0 set name = "Misa", nick = ':name', born = 1977` {
+    t.Fatalf("Unexpected statement %q (%v).", text, err)
+  }
+
+  for _, query := range []string{"update #11:0 set name = :name", "update #11:0 set name = :nick, born = :born", "update #11:0 set name = ?, born = :born"} {
+    if _, err = bindArgs(query, named); err != ErrMissingArgument {
+      t.Fatalf("Expecting ErrMissingArgument for %q, got %v.", query, err)
+    }
+  }
+}
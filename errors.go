@@ -0,0 +1,269 @@
+package goog
+
+import (
+  "encoding/json"
+  "errors"
+  "fmt"
+  "net/http"
+  "regexp"
+  "strconv"
+  "strings"
+
+  "github.com/hiphoox/goog/rest"
+)
+
+var (
+  // ErrUnknownLanguage is returned when a command is sent in a language
+  // other than SQL, GREMLIN or SCRIPT.
+  ErrUnknownLanguage = errors.New(`Unknown command language.`)
+
+  // ErrInvalidRID is returned when a string can't be parsed as a record id
+  // (#<cluster id>:<cluster position>).
+  ErrInvalidRID = errors.New(`Invalid record id`)
+
+  // ErrInvalidFetchPlan is returned when a fetch plan rule can't be parsed
+  // ([levels]field:depth).
+  ErrInvalidFetchPlan = errors.New(`Invalid fetch plan`)
+
+  // ErrNoServer is returned when connecting without giving a server address.
+  ErrNoServer = errors.New(`No server to connect to.`)
+
+  // ErrInvalidDSN is matched by the *DSNError returned when a connection
+  // string can't be parsed.
+  ErrInvalidDSN = errors.New(`Invalid DSN.`)
+
+  // ErrUnknownScheme is returned when a Config's scheme is neither HTTP nor
+  // HTTPS.
+  ErrUnknownScheme = errors.New(`Unknown scheme`)
+
+  // ErrInvalidIdentifier is returned when a class or property name is
+  // missing or can't be used in a SQL statement.
+  ErrInvalidIdentifier = errors.New(`Invalid identifier`)
+
+  // ErrInvalidDirection is returned when following edges in a direction
+  // other than OUT, IN or BOTH.
+  ErrInvalidDirection = errors.New(`Invalid direction`)
+
+  // ErrNoResult is returned when the server didn't answer with the record a
+  // command was expected to return.
+  ErrNoResult = errors.New(`The server returned no record.`)
+
+  // ErrMalformedResult is returned when a response can't be read as a set of
+  // records.
+  ErrMalformedResult = errors.New(`Malformed result`)
+
+  // ErrNoTarget is returned when running a traversal that doesn't say where
+  // to start from.
+  ErrNoTarget = errors.New(`Traversal has no target.`)
+
+  // ErrTxDone is returned when committing a transaction that was already
+  // committed or rolled back.
+  ErrTxDone = errors.New(`Transaction already committed or rolled back.`)
+
+  // ErrPoolClosed is returned when acquiring a session from a closed Pool.
+  ErrPoolClosed = errors.New(`Pool is closed.`)
+
+  // ErrReadOnlyTx is returned when database/sql asks for a read only
+  // transaction, which the batch endpoint can't provide.
+  ErrReadOnlyTx = errors.New(`Read only transactions are not supported.`)
+
+  // ErrNoLastInsertId is returned by the results of database/sql, records
+  // having ids rather than auto incremented keys.
+  ErrNoLastInsertId = errors.New(`LastInsertId is not supported, select the @rid of the record instead.`)
+
+  // ErrMissingArgument is returned when a statement has more or less
+  // placeholders than arguments.
+  ErrMissingArgument = errors.New(`Placeholders and arguments don't match.`)
+
+  // ErrUnsupportedArgument is returned when an argument can't be written as
+  // a SQL literal.
+  ErrUnsupportedArgument = errors.New(`Unsupported argument type`)
+
+  // ErrDestinationNotASlice is returned when attempting to decode records into
+  // something that is not a pointer to a slice.
+  ErrDestinationNotASlice = errors.New(`Destination is not a pointer to a slice.`)
+
+  // ErrDestinationNotAStruct is returned when attempting to decode a document
+  // into something that is not a pointer to a struct.
+  ErrDestinationNotAStruct = errors.New(`Destination is not a pointer to a struct.`)
+
+  // ErrConcurrentModification is matched by every *ConcurrentModificationError
+  // when using errors.Is.
+  ErrConcurrentModification = errors.New(`Record was modified concurrently.`)
+
+  // ErrUnauthorized is matched by a *CommandError when the server rejected
+  // the credentials or the session.
+  ErrUnauthorized = errors.New(`Unauthorized.`)
+
+  // ErrForbidden is matched by a *CommandError when the user lacks the
+  // permission a request needs. Logging in again wouldn't help, so such
+  // requests are never sent twice.
+  ErrForbidden = errors.New(`Forbidden.`)
+
+  // ErrNotFound is matched by a *CommandError when the server answered 404.
+  ErrNotFound = errors.New(`Not found.`)
+
+  // ErrDatabaseNotFound is matched by a *CommandError when the database being
+  // used does not exist on the server.
+  ErrDatabaseNotFound = errors.New(`Database not found.`)
+)
+
+var (
+  // javaClass matches the exception class OrientDB puts in front of its
+  // error messages.
+  javaClass = regexp.MustCompile(`^[\w$]+(\.[\w$]+)+$`)
+
+  // conflictRecord and conflictVersions find the record and versions in an
+  // OConcurrentModificationException message: Cannot UPDATE the record #11:4
+  // because the version is not the latest [...] (db=v3 your=v2).
+  conflictRecord   = regexp.MustCompile(`#-?\d+:-?\d+`)
+  conflictVersions = regexp.MustCompile(`db=v?(-?\d+)\s+your=v?(-?\d+)`)
+
+  // missingDatabase matches the messages OrientDB uses for databases that
+  // don't exist.
+  missingDatabase = regexp.MustCompile(`(?i)database .*(not exist|not found|not present)`)
+)
+
+// CommandError is returned when OrientDB rejects a request. Exception and
+// Message are read from the {"errors": [...]} payload of the response, Err is
+// the underlying *rest.HTTPError.
+//
+// Use errors.Is with ErrUnauthorized, ErrForbidden, ErrNotFound and
+// ErrDatabaseNotFound to tell the common cases apart.
+type CommandError struct {
+  StatusCode int
+  Exception  string
+  Message    string
+  Err        *rest.HTTPError
+
+  // database is set when the error is known to be about the database itself.
+  database bool
+}
+
+func (e *CommandError) Error() string {
+  if e.Exception == "" {
+    return e.Err.Status + ": " + e.Message
+  }
+
+  return e.Err.Status + ": " + e.Exception[strings.LastIndex(e.Exception, ".")+1:] + ": " + e.Message
+}
+
+// Unwrap gives access to the underlying *rest.HTTPError.
+func (e *CommandError) Unwrap() error {
+  return e.Err
+}
+
+// Is makes errors.Is work with ErrUnauthorized, ErrForbidden, ErrNotFound and
+// ErrDatabaseNotFound.
+func (e *CommandError) Is(target error) bool {
+  switch target {
+  case ErrUnauthorized:
+    return e.StatusCode == http.StatusUnauthorized
+  case ErrForbidden:
+    return e.StatusCode == http.StatusForbidden
+  case ErrNotFound:
+    return e.StatusCode == http.StatusNotFound
+  case ErrDatabaseNotFound:
+    return e.database || missingDatabase.MatchString(e.Message)
+  }
+
+  return false
+}
+
+// ConcurrentModificationError is returned when a record can't be updated
+// because its stored version is not the one the update was based on, meaning
+// somebody else modified it in between. Actual is -1 when the server didn't
+// report the stored version.
+type ConcurrentModificationError struct {
+  RID      RID
+  Expected int
+  Actual   int
+}
+
+func (e *ConcurrentModificationError) Error() string {
+  return fmt.Sprintf("Record %s was modified concurrently: expected version %d, found %d.", e.RID, e.Expected, e.Actual)
+}
+
+// Is makes errors.Is(err, ErrConcurrentModification) hold.
+func (e *ConcurrentModificationError) Is(target error) bool {
+  return target == ErrConcurrentModification
+}
+
+// newConcurrentModificationError reads the record and versions from the
+// message of an OConcurrentModificationException. Whatever can't be found is
+// left as the zero RID or -1.
+func newConcurrentModificationError(message string) *ConcurrentModificationError {
+  err := &ConcurrentModificationError{Expected: -1, Actual: -1}
+
+  if rid, parseErr := ParseRID(conflictRecord.FindString(message)); parseErr == nil {
+    err.RID = rid
+  }
+
+  if m := conflictVersions.FindStringSubmatch(message); m != nil {
+    err.Actual, _ = strconv.Atoi(m[1])
+    err.Expected, _ = strconv.Atoi(m[2])
+  }
+
+  return err
+}
+
+// serverError turns a *rest.HTTPError into a *CommandError, or into a
+// *ConcurrentModificationError for version conflicts. Other errors are
+// returned as they are.
+func serverError(err error) error {
+  var httpErr *rest.HTTPError
+
+  if !errors.As(err, &httpErr) {
+    return err
+  }
+
+  e := &CommandError{StatusCode: httpErr.StatusCode, Err: httpErr}
+  e.Exception, e.Message = parseErrorPayload(httpErr.Body)
+
+  if e.Message == "" {
+    e.Message = http.StatusText(httpErr.StatusCode)
+  }
+
+  // Other exceptions, such as duplicated keys, are also answered with a 409.
+  conflict := strings.HasSuffix(e.Exception, "OConcurrentModificationException") ||
+    (httpErr.StatusCode == http.StatusConflict && e.Exception == "")
+
+  if conflict {
+    return newConcurrentModificationError(e.Message)
+  }
+
+  return e
+}
+
+// parseErrorPayload reads the exception class and message from an error
+// response, given either as {"errors": [{"content": "..."}]} or as plain text.
+func parseErrorPayload(body []byte) (exception, message string) {
+  var payload struct {
+    Errors []struct {
+      Content string `json:"content"`
+    } `json:"errors"`
+  }
+
+  content := strings.TrimSpace(string(body))
+  if json.Unmarshal(body, &payload) == nil && len(payload.Errors) > 0 {
+    content = strings.TrimSpace(payload.Errors[0].Content)
+  }
+
+  if i := strings.Index(content, ": "); i > 0 && javaClass.MatchString(content[:i]) {
+    return content[:i], strings.TrimSpace(content[i+2:])
+  }
+
+  return "", content
+}
+
+// databaseError marks a 404 answered to a request about a whole database as
+// an ErrDatabaseNotFound.
+func databaseError(err error) error {
+  var cmdErr *CommandError
+
+  if errors.As(err, &cmdErr) && cmdErr.StatusCode == http.StatusNotFound {
+    cmdErr.database = true
+  }
+
+  return err
+}
@@ -0,0 +1,119 @@
+package goog
+
+import (
+  "errors"
+  "net/http"
+  "net/http/httptest"
+  "strings"
+  "testing"
+
+  "github.com/hiphoox/goog/rest"
+)
+
+func TestServerErrors(t *testing.T) {
+  ts, db := connectTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    switch {
+    case strings.HasPrefix(r.URL.Path, "/document/"):
+      w.WriteHeader(http.StatusNotFound)
+      w.Write([]byte(`{"errors": [{"code": 404, "reason": 404, "content": "Record with id #11:9 was not found"}]}`))
+    case strings.HasPrefix(r.URL.Path, "/query/"):
+      w.WriteHeader(http.StatusInternalServerError)
+      w.Write([]byte(`{"errors": [{"code": 500, "reason": 500, "content": "com.orientechnologies.orient.core.sql.OCommandSQLParsingException: Error on parsing command at position #7"}]}`))
+    default:
+      w.WriteHeader(http.StatusUnauthorized)
+      w.Write([]byte(`401 Unauthorized.`))
+    }
+  })
+  defer ts.Close()
+
+  _, err := db.LoadDocument(RID{11, 9}, "")
+  if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrUnauthorized) {
+    t.Fatalf("Expecting ErrNotFound, got %v.", err)
+  }
+
+  var httpErr *rest.HTTPError
+  if !errors.As(err, &httpErr) || httpErr.Method != "GET" {
+    t.Fatalf("Expecting the underlying *rest.HTTPError, got %v.", err)
+  }
+
+  _, err = db.Query("selec from Person", 0, "")
+
+  var cmdErr *CommandError
+  if !errors.As(err, &cmdErr) {
+    t.Fatalf("Expecting a *CommandError, got %v.", err)
+  }
+
+  if cmdErr.StatusCode != 500 || cmdErr.Exception != "com.orientechnologies.orient.core.sql.OCommandSQLParsingException" || cmdErr.Message != "Error on parsing command at position #7" {
+    t.Fatalf("Unexpected command error %#v.", cmdErr)
+  }
+
+  if cmdErr.Error() != "500 Internal Server Error: OCommandSQLParsingException: Error on parsing command at position #7" {
+    t.Fatalf("Unexpected message %q.", cmdErr.Error())
+  }
+
+  if _, err = db.Command(SQL, "delete from Person"); !errors.Is(err, ErrUnauthorized) {
+    t.Fatalf("Expecting ErrUnauthorized, got %v.", err)
+  }
+}
+
+func TestConflictErrors(t *testing.T) {
+  conflict := serverError(&rest.HTTPError{
+    StatusCode: http.StatusConflict,
+    Body:       []byte(`{"errors": [{"code": 409, "reason": 409, "content": "com.orientechnologies.orient.core.exception.OConcurrentModificationException: Cannot UPDATE the record #11:0 because the version is not the latest. Probably you are updating an old record or it has been modified by another user (db=v3 your=v2)"}]}`),
+  })
+  if !errors.Is(conflict, ErrConcurrentModification) {
+    t.Fatalf("Expecting ErrConcurrentModification, got %v.", conflict)
+  }
+
+  duplicated := serverError(&rest.HTTPError{
+    StatusCode: http.StatusConflict,
+    Body:       []byte(`{"errors": [{"code": 409, "reason": 409, "content": "com.orientechnologies.orient.core.storage.ORecordDuplicatedException: Cannot index record Person{name:Misa}: found duplicated key 'Misa' in index 'Person.name'"}]}`),
+  })
+
+  var cmdErr *CommandError
+  if errors.Is(duplicated, ErrConcurrentModification) || !errors.As(duplicated, &cmdErr) || cmdErr.StatusCode != http.StatusConflict {
+    t.Fatalf("Expecting a *CommandError, got %v.", duplicated)
+  }
+}
+
+func TestConnectErrors(t *testing.T) {
+  ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    if r.URL.Path == "/connect/missing" {
+      w.WriteHeader(http.StatusNotFound)
+      return
+    }
+    w.WriteHeader(http.StatusUnauthorized)
+  }))
+  defer ts.Close()
+
+  server := strings.TrimPrefix(ts.URL, HTTP_PREFIX)
+
+  if _, err := Connect(server, "missing", login, password); !errors.Is(err, ErrDatabaseNotFound) {
+    t.Fatalf("Expecting ErrDatabaseNotFound, got %v.", err)
+  }
+
+  if _, err := Connect(server, database_name, login, "wrong"); !errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrDatabaseNotFound) {
+    t.Fatalf("Expecting ErrUnauthorized, got %v.", err)
+  }
+}
+
+func TestForbiddenErrors(t *testing.T) {
+  var commands int
+
+  ts, db := connectTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+    commands++
+    w.WriteHeader(http.StatusForbidden)
+    w.Write([]byte(`{"errors": [{"code": 403, "reason": 403, "content": "com.orientechnologies.orient.core.exception.OSecurityAccessException: User 'reader' does not have permission to execute the operation 'Create' against the resource: ResourceGeneric [name=SCHEMA]"}]}`))
+  })
+  defer ts.Close()
+
+  _, err := db.Command(SQL, "create class Person")
+  if !errors.Is(err, ErrForbidden) || errors.Is(err, ErrUnauthorized) {
+    t.Fatalf("Expecting ErrForbidden, got %v.", err)
+  }
+
+  if commands != 1 {
+    t.Fatalf("A forbidden command should not be sent again, was sent %d times.", commands)
+  }
+}
@@ -0,0 +1,40 @@
+package goog
+
+import (
+  "context"
+  "encoding/json"
+
+  l4g "code.google.com/p/log4go"
+  "github.com/hiphoox/goog/sql"
+)
+
+// Exec runs a statement built with the goog/sql package through the command
+// endpoint, sending its values as positional parameters instead of quoting
+// them into the text.
+func (db *DataBase) Exec(stmt sql.Statement) ([]*Document, error) {
+  l4g.Trace("Inside Exec")
+
+  return db.ExecContext(context.Background(), stmt)
+}
+
+// ExecContext is like Exec, the request being canceled when ctx is done.
+func (db *DataBase) ExecContext(ctx context.Context, stmt sql.Statement) ([]*Document, error) {
+  l4g.Trace("Inside ExecContext")
+  var res result
+
+  text, params, err := stmt.Build()
+  if err != nil {
+    return nil, err
+  }
+
+  body := []byte(text)
+  if len(params) > 0 {
+    if body, err = json.Marshal(commandRequest{Command: text, Parameters: params}); err != nil {
+      return nil, err
+    }
+  }
+
+  err = db.postRaw(ctx, &res, COMMAND_URL+db.name+"/"+SQL, body)
+
+  return res.Result, err
+}
@@ -0,0 +1,34 @@
+package goog
+
+import (
+  "io/ioutil"
+  "net/http"
+  "testing"
+
+  "github.com/hiphoox/goog/sql"
+)
+
+func TestExec(t *testing.T) {
+  var path, body string
+
+  ts, db := connectTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+    buf, _ := ioutil.ReadAll(r.Body)
+    path, body = r.URL.Path, string(buf)
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    w.Write([]byte(`{"result": [{"@type": "d", "@rid": "#11:0", "@version": 1, "name": "Misa"}]}`))
+  })
+  defer ts.Close()
+
+  docs, err := db.Exec(sql.Select().From("Person").Where(sql.Eq("@rid", RID{11, 0})))
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if path != "/command/geneology/sql" || body != `{"command":"select from Person where @rid = ?","parameters":["#11:0"]}` {
+    t.Fatalf("Unexpected request %q %q.", path, body)
+  }
+
+  if len(docs) != 1 || docs[0].GetString("name") != "Misa" {
+    t.Fatalf("Unexpected records %v.", docs)
+  }
+}
@@ -0,0 +1,88 @@
+package goog
+
+import (
+  "fmt"
+  "regexp"
+  "strconv"
+  "strings"
+)
+
+// FetchPlan tells OrientDB which linked records to expand when it returns a
+// record. It is a list of rules written [levels]field:depth and separated by
+// spaces, e.g. *:-1 out_*:2 [0]in_*:-2:
+//
+//   - field is a field name, a prefix ending with * (out_*) or * for every
+//     field;
+//   - depth is how many links to follow from there, -1 meaning no limit and
+//     -2 excluding the field;
+//   - levels, optional, restricts the rule to some depths of the graph: [0],
+//     [1-3] or [*].
+//
+// Records expanded more than once are only written in full the first time,
+// later links to them being plain record ids; Query and LoadDocument resolve
+// those back to the records fetched, see Document.
+//
+// Query, QueryRows, Paginate, LoadDocument and Config take a FetchPlan, either
+// a literal such as "*:1" or one built from FETCH_ALL or FETCH_NONE.
+type FetchPlan string
+
+const (
+  // FETCH_ALL expands every link, however deep.
+  FETCH_ALL FetchPlan = "*:-1"
+
+  // FETCH_NONE returns every link as a record id.
+  FETCH_NONE FetchPlan = "*:0"
+)
+
+// fetchRule matches one rule of a fetch plan.
+var fetchRule = regexp.MustCompile(`^(\[(\*|\d+|\d+-\d*|-\d+)\])?(\*|[\w@]+(\.[\w@]+)*\*?):(-?\d+)$`)
+
+// ParseFetchPlan checks that s is a valid fetch plan and returns it.
+func ParseFetchPlan(s string) (FetchPlan, error) {
+  rules := strings.Fields(s)
+  if len(rules) == 0 {
+    return "", fmt.Errorf("%w %q.", ErrInvalidFetchPlan, s)
+  }
+
+  for _, rule := range rules {
+    match := fetchRule.FindStringSubmatch(rule)
+    if match == nil {
+      return "", fmt.Errorf("%w %q.", ErrInvalidFetchPlan, rule)
+    }
+
+    if depth, err := strconv.Atoi(match[5]); err != nil || depth < -2 {
+      return "", fmt.Errorf("%w %q.", ErrInvalidFetchPlan, rule)
+    }
+  }
+
+  return FetchPlan(strings.Join(rules, " ")), nil
+}
+
+// Field returns the plan with a rule expanding field (or every field starting
+// with a prefix, as in out_*) depth links deep:
+//
+//	FETCH_NONE.Field("out_*", 2).Field("in_*", -2)
+//
+// compiles to *:0 out_*:2 in_*:-2.
+func (plan FetchPlan) Field(field string, depth int) FetchPlan {
+  return plan.add(field + ":" + strconv.Itoa(depth))
+}
+
+// Level is like Field, the rule only applying to the records found at the
+// given depths of the graph: "0", "1-3" or "*".
+func (plan FetchPlan) Level(levels, field string, depth int) FetchPlan {
+  return plan.add("[" + levels + "]" + field + ":" + strconv.Itoa(depth))
+}
+
+func (plan FetchPlan) add(rule string) FetchPlan {
+  if plan == "" {
+    return FetchPlan(rule)
+  }
+
+  return plan + " " + FetchPlan(rule)
+}
+
+// String returns the plan as it is written in a request.
+func (plan FetchPlan) String() string {
+  return string(plan)
+}
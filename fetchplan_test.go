@@ -0,0 +1,123 @@
+package goog
+
+import (
+  "encoding/json"
+  "errors"
+  "net/http"
+  "strings"
+  "testing"
+)
+
+func TestFetchPlan(t *testing.T) {
+  if plan := FETCH_NONE.Field("out_*", 2).Level("0", "in_*", -2); plan.String() != "*:0 out_*:2 [0]in_*:-2" {
+    t.Fatalf("Unexpected fetch plan %q.", plan)
+  }
+
+  if plan := FetchPlan("").Field("best", 1); plan != "best:1" {
+    t.Fatalf("Unexpected fetch plan %q.", plan)
+  }
+
+  for _, s := range []string{"*:-1", " *:-1  out_*:2 ", "[*]in_*:-2", "[1-3]address.city:0", "@rid:0"} {
+    if _, err := ParseFetchPlan(s); err != nil {
+      t.Fatalf("Unexpected error parsing %q: %s", s, err)
+    }
+  }
+
+  if plan, _ := ParseFetchPlan(" *:-1  out_*:2 "); plan != "*:-1 out_*:2" {
+    t.Fatalf("Unexpected fetch plan %q.", plan)
+  }
+
+  for _, s := range []string{"", "*", "out:x", "out:-3", "[a]out:1", "out-in:1", "*:1 out"} {
+    if _, err := ParseFetchPlan(s); !errors.Is(err, ErrInvalidFetchPlan) {
+      t.Fatalf("Expecting ErrInvalidFetchPlan parsing %q, got %v.", s, err)
+    }
+  }
+}
+
+func TestFetchPlanCache(t *testing.T) {
+  var path string
+
+  ts, db := connectTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+    path = r.URL.Path
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    if strings.HasPrefix(path, "/document/") {
+      w.Write([]byte(`{"@type": "d", "@rid": "#11:0", "@version": 1, "@class": "Person", "@fieldTypes": "out=z,best=x",
+        "name": "Misa", "best": "#11:0",
+        "out": [{"@type": "d", "@rid": "#12:0", "@version": 1, "@class": "Knows", "@fieldTypes": "in=x", "in": "#11:0"}]}`))
+      return
+    }
+    w.Write([]byte(`{"result": [
+      {"@type": "d", "@rid": "#11:0", "@version": 1, "@class": "Person", "@fieldTypes": "out=z,best=x",
+       "name": "Misa", "best": "#11:1",
+       "out": [{"@type": "d", "@rid": "#11:1", "@version": 1, "@class": "Person", "@fieldTypes": "out=z", "name": "Beto", "out": ["#11:0", "#11:9"]}, "#11:2"]},
+      {"@type": "d", "@rid": "#11:2", "@version": 1, "@class": "Person", "@fieldTypes": "best=x", "name": "Nor", "best": "#11:1"}
+    ]}`))
+  })
+  defer ts.Close()
+
+  records, err := db.Query("select from Person where name = 'Misa'", 0, FETCH_NONE.Field("out", -1))
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if path != "/query/geneology/sql/select from Person where name = 'Misa'/-1/*:0 out:-1" {
+    t.Fatalf("Unexpected path %q.", path)
+  }
+
+  misa, nor := records[0], records[1]
+
+  beto := misa.GetEmbedded("best")
+  if beto == nil || beto.GetString("name") != "Beto" || nor.GetEmbedded("best") != beto {
+    t.Fatalf("Link was not resolved to the fetched record: %v.", misa.Get("best"))
+  }
+
+  out := misa.GetDocuments("out")
+  if len(out) != 2 || out[0] != beto || out[1] != nor {
+    t.Fatalf("Links were not resolved to the fetched records: %v.", misa.Get("out"))
+  }
+
+  if links := misa.GetLinks("out"); len(links) != 2 || links[0] != (RID{11, 1}) || links[1] != (RID{11, 2}) {
+    t.Fatalf("Unexpected links %v.", links)
+  }
+
+  // Links to records that were not fetched stay record ids.
+  if back := beto.Get("out").([]interface{}); back[0] != misa || back[1] != (RID{11, 9}) {
+    t.Fatalf("Unexpected links %v.", back)
+  }
+
+  // Cycles are decoded once, then only by their metadata.
+  type person struct {
+    RID  RID     `goog:"@rid"`
+    Name string  `goog:"name"`
+    Best *person `goog:"best"`
+    Out  []RID   `goog:"out"`
+  }
+
+  var people []person
+  if err = decodeDocuments(records, &people); err != nil {
+    t.Fatal(err)
+  }
+
+  if people[0].Best == nil || people[0].Best.Name != "Beto" || len(people[0].Out) != 2 {
+    t.Fatalf("Unexpected person %#v.", people[0])
+  }
+
+  // Expanded records are written back as links.
+  buf, err := json.Marshal(misa)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if !strings.Contains(string(buf), `"best":"#11:1"`) || !strings.Contains(string(buf), `"out":["#11:1","#11:2"]`) {
+    t.Fatalf("Unexpected encoded document %s.", buf)
+  }
+
+  doc, err := db.LoadDocument(RID{11, 0}, FETCH_ALL)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if doc.GetEmbedded("best") != doc || doc.GetDocuments("out")[0].GetEmbedded("in") != doc {
+    t.Fatalf("Links were not resolved to the loaded record: %v.", doc.Fields)
+  }
+}
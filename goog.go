@@ -1,44 +1,299 @@
 package goog
 
 import (
-  "github.com/hiphoox/goog/rest"
+  "context"
+  "errors"
+  "net/http"
+  "net/url"
+
   l4g "code.google.com/p/log4go"
+  "github.com/hiphoox/goog/rest"
 )
 
 const (
-  CONNECT_URL = "/connect/"
-  HTTP_PREFIX = "http://"
+  CONNECT_URL    = "/connect/"
+  DISCONNECT_URL = "/disconnect"
+  QUERY_URL      = "/query/"
+  COMMAND_URL    = "/command/"
+  DOCUMENT_URL   = "/document/"
+  CLASS_URL      = "/class/"
+  BATCH_URL      = "/batch/"
+
+  LIST_DATABASES_URL = "/listDatabases"
+  DATABASE_URL       = "/database/"
+  SERVER_URL         = "/server"
+
+  HTTP_PREFIX    = "http://"
+  HTTPS_PREFIX   = "https://"
+  SESSION_COOKIE = "OSESSIONID"
 )
 
 type DataBase struct {
-  name       string
-  server      string
-  client     *rest.Client
+  name     string
+  server   string
+  cluster  *cluster
+  login    string
+  password string
+
+  // fetchPlan is used when a query or load is given none.
+  fetchPlan FetchPlan
 }
 
-func Connect(server, database_name, login, password string) (DataBase, error) {
+// Connect opens a session with the database on the given server (host:port).
+// Options configure the underlying rest.Client, e.g. rest.WithRetryPolicy() to
+// retry requests failing with transient errors.
+func Connect(server, database_name, login, password string, options ...rest.ClientOption) (DataBase, error) {
   l4g.Trace("Inside Connect")
+
+  return ConnectContext(context.Background(), server, database_name, login, password, options...)
+}
+
+// ConnectContext is like Connect, giving up when ctx is done.
+func ConnectContext(ctx context.Context, server, database_name, login, password string, options ...rest.ClientOption) (DataBase, error) {
+  l4g.Trace("Inside ConnectContext")
+
+  return dial(ctx, Config{Server: server, Database: database_name, Login: login, Password: password, Options: options})
+}
+
+// dial opens a session with the database described by config, on the first
+// of its servers that can be reached.
+func dial(ctx context.Context, config Config) (DataBase, error) {
   var db DataBase
 
-  // Create client
-  client, err := rest.New(HTTP_PREFIX + server)
+  // Create clients
+  cluster, err := newCluster(config)
 
   if err == nil {
-    l4g.Trace("Getting token...")
-    client.SetBasicAuth(login, password)
-    err = client.GetHeaders(CONNECT_URL + database_name)
+    candidate := DataBase{name: config.Database,
+      server:    config.Server,
+      cluster:   cluster,
+      login:     config.Login,
+      password:  config.Password,
+      fetchPlan: config.FetchPlan}
+
+    err = candidate.do(ctx, true, nil)
 
     if err == nil {
-     l4g.Trace("Creating database structure...")
-      db = DataBase{  name: database_name, 
-                    server: server,
-                    client: client}
+      l4g.Trace("Creating database structure...")
+      db = candidate
+    }
+  }
+
+  return db, err
+}
+
+// ConnectWithToken resumes a session opened by Connect, given the token
+// GetToken returned. Without credentials the session can't be renewed once it
+// expires: requests then fail with ErrUnauthorized.
+func ConnectWithToken(server, database_name, token string) (DataBase, error) {
+  l4g.Trace("Inside ConnectWithToken")
+
+  return ConnectWithTokenConfig(Config{Server: server, Database: database_name}, token)
+}
+
+// ConnectWithTokenConfig is like ConnectWithToken, reaching the database the
+// way config describes. When config has credentials, they are used to log in
+// again once the resumed session expires.
+func ConnectWithTokenConfig(config Config, token string) (DataBase, error) {
+  l4g.Trace("Inside ConnectWithTokenConfig")
+  var db DataBase
+
+  cluster, err := newCluster(config)
+
+  if err == nil {
+    for _, n := range cluster.nodes {
+      if err = n.client.SetCookie(&http.Cookie{Name: SESSION_COOKIE, Value: token, Path: "/"}); err != nil {
+        return db, err
+      }
+      n.session = true
     }
+
+    db = DataBase{name: config.Database,
+      server:    config.Server,
+      cluster:   cluster,
+      login:     config.Login,
+      password:  config.Password,
+      fetchPlan: config.FetchPlan}
   }
 
   return db, err
 }
 
+// GetToken returns the id of the session opened with the server, which
+// ConnectWithToken can resume. With several servers, it is the session with
+// the server the last request went to.
 func (db *DataBase) GetToken() string {
-    return db.client.GetHeader("Set-Cookie")
+  if cookie := db.cluster.primary().client.Cookie(SESSION_COOKIE); cookie != nil {
+    return cookie.Value
+  }
+
+  return ""
+}
+
+// Close ends the sessions with the servers. The DataBase can't be used
+// afterwards.
+func (db *DataBase) Close() error {
+  l4g.Trace("Inside Close")
+  var err error
+
+  for _, n := range db.cluster.nodes {
+    if db.cluster.hasSession(n) {
+      closeErr := serverError(n.client.GetHeaders(DISCONNECT_URL))
+
+      // OrientDB answers 401 on purpose, so that browsers forget the
+      // credentials.
+      if closeErr != nil && !errors.Is(closeErr, ErrUnauthorized) && err == nil {
+        err = closeErr
+      }
+    }
+
+    db.cluster.setSession(n, false)
+    n.client.ClearBasicAuth()
+
+    if clearErr := n.client.ClearCookies(); err == nil {
+      err = clearErr
+    }
+  }
+
+  db.login, db.password = "", ""
+
+  return err
+}
+
+// Ping checks that the server still accepts the session.
+func (db *DataBase) Ping() error {
+  return db.PingContext(context.Background())
+}
+
+// PingContext is like Ping, giving up when ctx is done.
+func (db *DataBase) PingContext(ctx context.Context) error {
+  return databaseError(db.do(ctx, false, func(client *rest.Client) error {
+    return client.GetHeadersContext(ctx, CONNECT_URL+db.name)
+  }))
+}
+
+// authenticate opens a new session with the given server.
+func (db *DataBase) authenticate(ctx context.Context, n *node) error {
+  n.auth.Lock()
+  defer n.auth.Unlock()
+
+  return db.openSession(ctx, n)
+}
+
+// reauthenticate is like authenticate, unless a session was opened with the
+// server since the caller saw logins of them: requests rejected together then
+// only log in once.
+func (db *DataBase) reauthenticate(ctx context.Context, n *node, logins int) error {
+  n.auth.Lock()
+  defer n.auth.Unlock()
+
+  if db.cluster.loginsTo(n) != logins {
+    return nil
+  }
+
+  return db.openSession(ctx, n)
+}
+
+// openSession does the work of authenticate, n.auth being held.
+func (db *DataBase) openSession(ctx context.Context, n *node) error {
+  l4g.Trace("Getting token from %s...", n.address)
+
+  db.cluster.setSession(n, false)
+
+  if err := n.client.ClearCookies(); err != nil {
+    return err
+  }
+
+  if db.login != "" {
+    n.client.SetBasicAuth(db.login, db.password)
+  }
+
+  err := databaseError(serverError(n.client.GetHeadersContext(ctx, CONNECT_URL+db.name)))
+  if err == nil {
+    db.cluster.setSession(n, true)
+  }
+
+  return err
+}
+
+// The following helpers are the only way DataBase talks to the server: they
+// turn error responses into the errors described in errors.go, log in again
+// when the session expired and move to another server when one goes down.
+
+func (db *DataBase) get(ctx context.Context, dst interface{}, path string, data url.Values) error {
+  return db.do(ctx, false, func(client *rest.Client) error { return client.GetContext(ctx, dst, path, data) })
+}
+
+func (db *DataBase) postRaw(ctx context.Context, dst interface{}, path string, body []byte) error {
+  return db.do(ctx, true, func(client *rest.Client) error { return client.PostRawContext(ctx, dst, path, body) })
+}
+
+func (db *DataBase) putRaw(ctx context.Context, dst interface{}, path string, body []byte) error {
+  return db.do(ctx, true, func(client *rest.Client) error { return client.PutRawContext(ctx, dst, path, body) })
+}
+
+func (db *DataBase) patchRaw(ctx context.Context, dst interface{}, path string, body []byte) error {
+  return db.do(ctx, true, func(client *rest.Client) error { return client.PatchRawContext(ctx, dst, path, body) })
+}
+
+func (db *DataBase) delete(ctx context.Context, dst interface{}, path string) error {
+  return db.do(ctx, true, func(client *rest.Client) error { return client.DeleteContext(ctx, dst, path, nil) })
+}
+
+// do runs a request on the servers chosen by the routing strategy, in turn.
+// A server that can't be reached is marked down and the request is sent to
+// the next one: reads whenever the connection was lost, writes only when they
+// could not have been received. A nil request only opens a session.
+func (db *DataBase) do(ctx context.Context, write bool, request func(*rest.Client) error) error {
+  var err error
+
+  for _, n := range db.cluster.candidates(write) {
+    err = db.doOn(ctx, n, request)
+
+    if err == nil || ctx.Err() != nil || !(unreachable(err) || (!write && disconnected(err))) {
+      return err
+    }
+
+    db.cluster.markDown(n)
+    l4g.Trace("Failing over from %s: %s", n.address, err)
+  }
+
+  return err
+}
+
+// doOn runs a request on the given server, opening a session with it first
+// if we have none and still have credentials. If the server rejects the
+// session, it logs in again and retries the request once.
+func (db *DataBase) doOn(ctx context.Context, n *node, request func(*rest.Client) error) error {
+  if request == nil {
+    if err := db.authenticate(ctx, n); err != nil {
+      return err
+    }
+  } else if logins := db.cluster.loginsTo(n); !db.cluster.hasSession(n) && db.login != "" {
+    if err := db.reauthenticate(ctx, n, logins); err != nil {
+      return err
+    }
+  }
+
+  var err error
+  if request != nil {
+    logins := db.cluster.loginsTo(n)
+    err = serverError(request(n.client))
+
+    if errors.Is(err, ErrUnauthorized) && db.login != "" {
+      l4g.Trace("Session expired, logging in again...")
+
+      if authErr := db.reauthenticate(ctx, n, logins); authErr != nil {
+        return authErr
+      }
+
+      err = serverError(request(n.client))
+    }
+  }
+
+  if !disconnected(err) {
+    db.cluster.markUp(n)
+  }
+
+  return err
 }
@@ -1,9 +1,16 @@
 package goog
 
 import (
+  "errors"
   "testing"
   "fmt"
+  "net/http"
+  "net/http/httptest"
+  "strings"
+  "sync"
+  "time"
   l4g "code.google.com/p/log4go"
+  "github.com/hiphoox/goog/rest"
 )
 
 const (
@@ -29,4 +36,194 @@ func TestConnect(t *testing.T) {
 
     token :=  client.GetToken()
     l4g.Info(token)
-}
\ No newline at end of file
+}
+
+// connectTestServer starts a fake OrientDB server that accepts any credentials
+// on /connect/ and hands every other request to handler.
+func connectTestServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, DataBase) {
+  ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    if strings.HasPrefix(r.URL.Path, CONNECT_URL) {
+      http.SetCookie(w, &http.Cookie{Name: "OSESSIONID", Value: "OS1234", Path: "/"})
+      w.WriteHeader(http.StatusNoContent)
+      return
+    }
+    handler(w, r)
+  }))
+
+  db, err := Connect(strings.TrimPrefix(ts.URL, HTTP_PREFIX), database_name, login, password)
+  if err != nil {
+    ts.Close()
+    t.Fatal("Failed to connect to test server:", err)
+  }
+
+  return ts, db
+}
+
+func TestSession(t *testing.T) {
+  sessions := map[string]bool{}
+  var connects, disconnects int
+
+  ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    switch {
+    case strings.HasPrefix(r.URL.Path, CONNECT_URL):
+      connects++
+      id := fmt.Sprintf("OS%d", connects)
+      sessions[id] = true
+      http.SetCookie(w, &http.Cookie{Name: SESSION_COOKIE, Value: id, Path: "/"})
+      w.WriteHeader(http.StatusNoContent)
+    case r.URL.Path == DISCONNECT_URL:
+      disconnects++
+      if cookie, err := r.Cookie(SESSION_COOKIE); err == nil {
+        delete(sessions, cookie.Value)
+      }
+      w.WriteHeader(http.StatusUnauthorized)
+    default:
+      if cookie, err := r.Cookie(SESSION_COOKIE); err != nil || !sessions[cookie.Value] {
+        w.WriteHeader(http.StatusUnauthorized)
+        return
+      }
+      w.Header().Set("Content-Type", "application/json; charset=utf-8")
+      w.Write([]byte(`{"result": []}`))
+    }
+  }))
+  defer ts.Close()
+
+  address := strings.TrimPrefix(ts.URL, HTTP_PREFIX)
+
+  db, err := Connect(address, database_name, login, password)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  token := db.GetToken()
+  if token != "OS1" {
+    t.Fatalf("Unexpected token %q.", token)
+  }
+
+  resumed, err := ConnectWithToken(address, database_name, token)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if _, err = resumed.Query("select from Person", 0, ""); err != nil || connects != 1 {
+    t.Fatalf("Resumed session should be reused (%v, %d connects).", err, connects)
+  }
+
+  // The server forgets the session: the next request logs in again.
+  delete(sessions, token)
+
+  if _, err = db.Query("select from Person", 0, ""); err != nil || connects != 2 || db.GetToken() != "OS2" {
+    t.Fatalf("Expecting a new session (%v, %d connects, token %q).", err, connects, db.GetToken())
+  }
+
+  // Without credentials there is no way to renew it.
+  if _, err = resumed.Query("select from Person", 0, ""); !errors.Is(err, ErrUnauthorized) {
+    t.Fatalf("Expecting ErrUnauthorized, got %v.", err)
+  }
+
+  if err = db.Close(); err != nil {
+    t.Fatal(err)
+  }
+
+  if disconnects != 1 || len(sessions) != 0 || db.GetToken() != "" {
+    t.Fatalf("Session was not closed (%d disconnects, %v).", disconnects, sessions)
+  }
+
+  if _, err = db.Query("select from Person", 0, ""); !errors.Is(err, ErrUnauthorized) || connects != 2 {
+    t.Fatalf("A closed DataBase should not log in again (%v, %d connects).", err, connects)
+  }
+}
+
+func TestConcurrentSessions(t *testing.T) {
+  var mu sync.Mutex
+  var connects, served int
+  session := ""
+
+  ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    mu.Lock()
+    defer mu.Unlock()
+
+    if strings.HasPrefix(r.URL.Path, CONNECT_URL) {
+      connects++
+      session = fmt.Sprintf("OS%d", connects)
+      http.SetCookie(w, &http.Cookie{Name: SESSION_COOKIE, Value: session, Path: "/"})
+      w.WriteHeader(http.StatusNoContent)
+      return
+    }
+
+    if cookie, err := r.Cookie(SESSION_COOKIE); err != nil || cookie.Value != session {
+      w.WriteHeader(http.StatusUnauthorized)
+      return
+    }
+
+    // The first three sessions expire after serving 25 requests.
+    if served++; served%25 == 0 && connects < 4 {
+      session = ""
+    }
+
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    w.Write([]byte(`{"result": []}`))
+  }))
+  defer ts.Close()
+
+  db, err := Connect(strings.TrimPrefix(ts.URL, HTTP_PREFIX), database_name, login, password)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  var wg sync.WaitGroup
+  errs := make(chan error, 8)
+
+  for i := 0; i < 8; i++ {
+    wg.Add(1)
+    go func() {
+      defer wg.Done()
+      for j := 0; j < 10; j++ {
+        if _, err := db.Query("select from Person", 0, ""); err != nil {
+          errs <- err
+          return
+        }
+      }
+    }()
+  }
+
+  wg.Wait()
+  close(errs)
+
+  for err := range errs {
+    t.Fatal(err)
+  }
+
+  // Requests rejected together log in once.
+  if connects != 4 {
+    t.Fatalf("Expecting a login per expired session, got %d.", connects)
+  }
+}
+
+func TestConnectRetry(t *testing.T) {
+  var connects int
+
+  ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    connects++
+    if connects == 1 {
+      w.WriteHeader(http.StatusServiceUnavailable)
+      return
+    }
+    http.SetCookie(w, &http.Cookie{Name: SESSION_COOKIE, Value: "OS1", Path: "/"})
+    w.WriteHeader(http.StatusNoContent)
+  }))
+  defer ts.Close()
+
+  address := strings.TrimPrefix(ts.URL, HTTP_PREFIX)
+
+  if _, err := Connect(address, database_name, login, password); err == nil {
+    t.Fatalf("Expecting the first attempt to fail.")
+  }
+
+  connects = 0
+  db, err := Connect(address, database_name, login, password,
+    rest.WithRetryPolicy(rest.RetryPolicy{InitialBackoff: time.Millisecond}))
+  if err != nil || connects != 2 || db.GetToken() != "OS1" {
+    t.Fatalf("Expecting a session after a retry (%v, %d connects).", err, connects)
+  }
+}
@@ -0,0 +1,199 @@
+package goog
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "regexp"
+
+  l4g "code.google.com/p/log4go"
+)
+
+// Direction tells which edges of a vertex to follow.
+type Direction string
+
+const (
+  OUT  Direction = "out"
+  IN   Direction = "in"
+  BOTH Direction = "both"
+)
+
+// identifier matches the class names that can be written unquoted in SQL.
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Graph is a graph shaped view over a database, whose vertex classes extend V
+// and whose edge classes extend E.
+type Graph struct {
+  db *DataBase
+}
+
+// Graph returns a graph view over the database.
+func (db *DataBase) Graph() *Graph {
+  return &Graph{db: db}
+}
+
+// CreateVertex creates a vertex of the given class (V when empty) with the
+// given properties and returns it.
+func (g *Graph) CreateVertex(class string, props map[string]interface{}) (*Document, error) {
+  l4g.Trace("Inside CreateVertex")
+
+  return g.CreateVertexContext(context.Background(), class, props)
+}
+
+// CreateVertexContext is like CreateVertex, the request being canceled when ctx
+// is done.
+func (g *Graph) CreateVertexContext(ctx context.Context, class string, props map[string]interface{}) (*Document, error) {
+  l4g.Trace("Inside CreateVertexContext")
+
+  if class == "" {
+    class = "V"
+  }
+
+  if err := checkIdentifiers(class); err != nil {
+    return nil, err
+  }
+
+  content, err := contentClause(props)
+  if err != nil {
+    return nil, err
+  }
+
+  return first(g.db.CommandContext(ctx, SQL, "create vertex "+class+content))
+}
+
+// CreateEdge creates an edge of the given class (E when empty) going from one
+// vertex to another, with the given properties, and returns it.
+func (g *Graph) CreateEdge(class string, from, to RID, props map[string]interface{}) (*Document, error) {
+  l4g.Trace("Inside CreateEdge")
+
+  return g.CreateEdgeContext(context.Background(), class, from, to, props)
+}
+
+// CreateEdgeContext is like CreateEdge, the request being canceled when ctx is
+// done.
+func (g *Graph) CreateEdgeContext(ctx context.Context, class string, from, to RID, props map[string]interface{}) (*Document, error) {
+  l4g.Trace("Inside CreateEdgeContext")
+
+  if class == "" {
+    class = "E"
+  }
+
+  if err := checkIdentifiers(class); err != nil {
+    return nil, err
+  }
+
+  content, err := contentClause(props)
+  if err != nil {
+    return nil, err
+  }
+
+  return first(g.db.CommandContext(ctx, SQL, "create edge "+class+" from "+from.String()+" to "+to.String()+content))
+}
+
+// Out returns the vertices reached from rid through its outgoing edges of the
+// given class, or through all of them when edgeClass is empty.
+func (g *Graph) Out(rid RID, edgeClass string) ([]*Document, error) {
+  return g.OutContext(context.Background(), rid, edgeClass)
+}
+
+// OutContext is like Out, the request being canceled when ctx is done.
+func (g *Graph) OutContext(ctx context.Context, rid RID, edgeClass string) ([]*Document, error) {
+  return g.neighbours(ctx, rid, OUT, edgeClass)
+}
+
+// In returns the vertices reaching rid through incoming edges of the given
+// class, or through all of them when edgeClass is empty.
+func (g *Graph) In(rid RID, edgeClass string) ([]*Document, error) {
+  return g.InContext(context.Background(), rid, edgeClass)
+}
+
+// InContext is like In, the request being canceled when ctx is done.
+func (g *Graph) InContext(ctx context.Context, rid RID, edgeClass string) ([]*Document, error) {
+  return g.neighbours(ctx, rid, IN, edgeClass)
+}
+
+// Both returns the vertices connected to rid by edges of the given class in
+// either direction, or by any edge when edgeClass is empty.
+func (g *Graph) Both(rid RID, edgeClass string) ([]*Document, error) {
+  return g.BothContext(context.Background(), rid, edgeClass)
+}
+
+// BothContext is like Both, the request being canceled when ctx is done.
+func (g *Graph) BothContext(ctx context.Context, rid RID, edgeClass string) ([]*Document, error) {
+  return g.neighbours(ctx, rid, BOTH, edgeClass)
+}
+
+// Edges returns the edge records attached to rid in the given direction.
+func (g *Graph) Edges(rid RID, direction Direction) ([]*Document, error) {
+  l4g.Trace("Inside Edges")
+
+  return g.EdgesContext(context.Background(), rid, direction)
+}
+
+// EdgesContext is like Edges, the request being canceled when ctx is done.
+func (g *Graph) EdgesContext(ctx context.Context, rid RID, direction Direction) ([]*Document, error) {
+  l4g.Trace("Inside EdgesContext")
+
+  if err := checkDirection(direction); err != nil {
+    return nil, err
+  }
+
+  return g.db.QueryContext(ctx, "select expand("+string(direction)+"E()) from "+rid.String(), 0, "")
+}
+
+func (g *Graph) neighbours(ctx context.Context, rid RID, direction Direction, edgeClass string) ([]*Document, error) {
+  l4g.Trace("Inside neighbours")
+
+  if err := checkDirection(direction); err != nil {
+    return nil, err
+  }
+
+  if err := checkIdentifiers(edgeClass); err != nil {
+    return nil, err
+  }
+
+  if edgeClass != "" {
+    edgeClass = "'" + edgeClass + "'"
+  }
+
+  return g.db.QueryContext(ctx, "select expand("+string(direction)+"("+edgeClass+")) from "+rid.String(), 0, "")
+}
+
+// contentClause renders props as a CONTENT clause; being JSON it needs no
+// further quoting.
+func contentClause(props map[string]interface{}) (string, error) {
+  if len(props) == 0 {
+    return "", nil
+  }
+
+  content, err := json.Marshal(props)
+  if err != nil {
+    return "", err
+  }
+
+  return " content " + string(content), nil
+}
+
+// first returns the first of the records a command answered with.
+func first(docs []*Document, err error) (*Document, error) {
+  if err != nil {
+    return nil, err
+  }
+
+  if len(docs) == 0 {
+    return nil, ErrNoResult
+  }
+
+  return docs[0], nil
+}
+
+// checkDirection returns ErrInvalidDirection unless direction is OUT, IN or
+// BOTH, as it is written into the query.
+func checkDirection(direction Direction) error {
+  switch direction {
+  case OUT, IN, BOTH:
+    return nil
+  }
+
+  return fmt.Errorf("%w %q.", ErrInvalidDirection, direction)
+}
@@ -0,0 +1,108 @@
+package goog
+
+import (
+  "context"
+  "errors"
+  "io/ioutil"
+  "net/http"
+  "testing"
+  "time"
+)
+
+func TestGraph(t *testing.T) {
+  var statements []string
+
+  ts, db := connectTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+    buf, _ := ioutil.ReadAll(r.Body)
+    if r.Method == "POST" {
+      statements = append(statements, string(buf))
+    } else {
+      statements = append(statements, r.URL.Path)
+    }
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    w.Write([]byte(`{"result": [{"@type": "d", "@rid": "#11:1", "@version": 1, "@class": "Person", "name": "Beto"}]}`))
+  })
+  defer ts.Close()
+
+  g := db.Graph()
+
+  v, err := g.CreateVertex("Person", map[string]interface{}{"name": "Beto"})
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if v.RID != (RID{11, 1}) {
+    t.Fatalf("Unexpected vertex %v.", v)
+  }
+
+  if _, err = g.CreateEdge("Referrer", RID{11, 0}, RID{11, 1}, nil); err != nil {
+    t.Fatal(err)
+  }
+
+  if _, err = g.Out(RID{11, 0}, "Referrer"); err != nil {
+    t.Fatal(err)
+  }
+
+  if _, err = g.Both(RID{11, 0}, ""); err != nil {
+    t.Fatal(err)
+  }
+
+  if _, err = g.Edges(RID{11, 0}, IN); err != nil {
+    t.Fatal(err)
+  }
+
+  expected := []string{
+    `create vertex Person content {"name":"Beto"}`,
+    `create edge Referrer from #11:0 to #11:1`,
+    `/query/geneology/sql/select expand(out('Referrer')) from #11:0/-1`,
+    `/query/geneology/sql/select expand(both()) from #11:0/-1`,
+    `/query/geneology/sql/select expand(inE()) from #11:0/-1`,
+  }
+
+  for i := range expected {
+    if statements[i] != expected[i] {
+      t.Fatalf("Expecting %q, got %q.", expected[i], statements[i])
+    }
+  }
+
+  if _, err = g.In(RID{11, 0}, "Referrer') from #0:0 --"); !errors.Is(err, ErrInvalidIdentifier) {
+    t.Fatalf("Expecting ErrInvalidIdentifier, got %v.", err)
+  }
+  for _, direction := range []Direction{"", "OUT", "out(), in"} {
+    if _, err = g.Edges(RID{11, 0}, direction); !errors.Is(err, ErrInvalidDirection) {
+      t.Fatalf("Expecting ErrInvalidDirection for %q, got %v.", direction, err)
+    }
+  }
+
+  if len(statements) != len(expected) {
+    t.Fatalf("Invalid requests should not be sent: %q.", statements[len(expected):])
+  }
+}
+
+func TestGraphContext(t *testing.T) {
+  ts, db := connectTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+    <-r.Context().Done()
+  })
+  defer ts.Close()
+
+  ctx, cancel := context.WithCancel(context.Background())
+  time.AfterFunc(20*time.Millisecond, cancel)
+
+  graph, schema := db.Graph(), db.Schema()
+
+  if _, err := graph.OutContext(ctx, RID{11, 0}, "Referrer"); !errors.Is(err, context.Canceled) {
+    t.Fatalf("Expecting context.Canceled, got %v.", err)
+  }
+
+  if _, err := graph.CreateVertexContext(ctx, "Person", nil); !errors.Is(err, context.Canceled) {
+    t.Fatalf("Expecting context.Canceled, got %v.", err)
+  }
+
+  if _, err := schema.ClassContext(ctx, "Person"); !errors.Is(err, context.Canceled) {
+    t.Fatalf("Expecting context.Canceled, got %v.", err)
+  }
+
+  if err := schema.CreateClassContext(ctx, "Person", "V", false); !errors.Is(err, context.Canceled) {
+    t.Fatalf("Expecting context.Canceled, got %v.", err)
+  }
+}
@@ -0,0 +1,325 @@
+package goog
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "reflect"
+  "strings"
+  "sync"
+  "time"
+
+  l4g "code.google.com/p/log4go"
+)
+
+var (
+  ridType      = reflect.TypeOf(RID{})
+  timeType     = reflect.TypeOf(time.Time{})
+  documentType = reflect.TypeOf(Document{})
+)
+
+// structFields caches the goog tag of every field of the struct types that
+// were already decoded, as a map from the tag to the field's index.
+var structFields sync.Map
+
+// QueryInto runs a query like Query does and decodes the records found into
+// dst, a pointer to a slice of structs (or of pointers to structs).
+//
+// Struct fields are matched by their `goog:"name"` tag, or by their name with
+// its first letter lower cased when they have none; `goog:"-"` skips a field.
+// The @rid, @version, @class and @type tags receive the record's metadata.
+// Link fields can be RID, []RID or structs: the latter are filled from the
+// records a fetch plan expanded and only get their @rid otherwise, as do
+// records linked back to while they are being decoded.
+func (db *DataBase) QueryInto(dst interface{}, sql string, limit int, fetchPlan FetchPlan) error {
+  l4g.Trace("Inside QueryInto")
+
+  return db.QueryIntoContext(context.Background(), dst, sql, limit, fetchPlan)
+}
+
+// QueryIntoContext is like QueryInto, the request being canceled when ctx is
+// done.
+func (db *DataBase) QueryIntoContext(ctx context.Context, dst interface{}, sql string, limit int, fetchPlan FetchPlan) error {
+  l4g.Trace("Inside QueryIntoContext")
+
+  docs, err := db.QueryContext(ctx, sql, limit, fetchPlan)
+  if err != nil {
+    return err
+  }
+
+  return decodeDocuments(docs, dst)
+}
+
+// decoder copies documents into Go values, keeping track of the documents
+// being decoded so that cycles among linked records end.
+type decoder struct {
+  active map[*Document]bool
+}
+
+func newDecoder() *decoder {
+  return &decoder{active: map[*Document]bool{}}
+}
+
+// decodeDocuments decodes docs into dst, a pointer to a slice.
+func decodeDocuments(docs []*Document, dst interface{}) error {
+  d := newDecoder()
+
+  rv := reflect.ValueOf(dst)
+
+  if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Slice {
+    return ErrDestinationNotASlice
+  }
+
+  slice := reflect.MakeSlice(rv.Elem().Type(), len(docs), len(docs))
+  for i, doc := range docs {
+    if err := d.assign(slice.Index(i), doc); err != nil {
+      return err
+    }
+  }
+
+  rv.Elem().Set(slice)
+
+  return nil
+}
+
+// Decode copies the document into dst, a pointer to a struct, following the
+// rules described on QueryInto.
+func (doc *Document) Decode(dst interface{}) error {
+  rv := reflect.ValueOf(dst)
+
+  if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+    return ErrDestinationNotAStruct
+  }
+
+  return newDecoder().decodeStruct(rv.Elem(), doc)
+}
+
+func (d *decoder) decodeStruct(dst reflect.Value, doc *Document) error {
+  // Links resolved by Query can lead back to a record being decoded, which
+  // then only gets its metadata.
+  if d.active[doc] {
+    doc = &Document{Type: doc.Type, RID: doc.RID, Version: doc.Version, Class: doc.Class}
+  } else {
+    d.active[doc] = true
+    defer delete(d.active, doc)
+  }
+
+  for tag, index := range fieldsOf(dst.Type()) {
+    var value interface{}
+
+    switch tag {
+    case "@rid":
+      value = doc.RID
+    case "@version":
+      value = doc.Version
+    case "@class":
+      value = doc.Class
+    case "@type":
+      value = doc.Type
+    default:
+      var ok bool
+      if value, ok = doc.Fields[tag]; !ok {
+        continue
+      }
+    }
+
+    if err := d.assign(dst.FieldByIndex(index), value); err != nil {
+      return fmt.Errorf("Field %q: %s", tag, err)
+    }
+  }
+
+  return nil
+}
+
+// fieldsOf returns the tags of the exported fields of a struct type, looking
+// into embedded structs the way encoding/json does.
+func fieldsOf(t reflect.Type) map[string][]int {
+  if fields, ok := structFields.Load(t); ok {
+    return fields.(map[string][]int)
+  }
+
+  fields := map[string][]int{}
+
+  for i := 0; i < t.NumField(); i++ {
+    field := t.Field(i)
+    tag := field.Tag.Get("goog")
+
+    if tag == "-" || (field.PkgPath != "" && !field.Anonymous) {
+      continue
+    }
+
+    if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct && field.Type != documentType {
+      for name, index := range fieldsOf(field.Type) {
+        if _, ok := fields[name]; !ok {
+          fields[name] = append([]int{i}, index...)
+        }
+      }
+      continue
+    }
+
+    if field.PkgPath != "" {
+      continue
+    }
+
+    if tag == "" {
+      tag = lowerFirst(field.Name)
+    }
+    fields[tag] = []int{i}
+  }
+
+  structFields.Store(t, fields)
+
+  return fields
+}
+
+// assign stores a decoded field value into dst, converting it as needed.
+func (d *decoder) assign(dst reflect.Value, value interface{}) error {
+  if value == nil {
+    dst.Set(reflect.Zero(dst.Type()))
+    return nil
+  }
+
+  switch dst.Type() {
+  case ridType:
+    switch v := value.(type) {
+    case RID:
+      dst.Set(reflect.ValueOf(v))
+      return nil
+    case *Document:
+      dst.Set(reflect.ValueOf(v.RID))
+      return nil
+    case string:
+      rid, err := ParseRID(v)
+      if err == nil {
+        dst.Set(reflect.ValueOf(rid))
+      }
+      return err
+    }
+  case documentType:
+    if v, ok := value.(*Document); ok {
+      dst.Set(reflect.ValueOf(*v))
+      return nil
+    }
+  case timeType:
+    switch v := value.(type) {
+    case time.Time:
+      dst.Set(reflect.ValueOf(v))
+      return nil
+    case string:
+      t, err := time.ParseInLocation(dateTimeLayout, v, time.Local)
+      if err == nil {
+        dst.Set(reflect.ValueOf(t))
+      }
+      return err
+    }
+  }
+
+  switch dst.Kind() {
+  case reflect.Ptr:
+    elem := reflect.New(dst.Type().Elem())
+    if err := d.assign(elem.Elem(), value); err != nil {
+      return err
+    }
+    dst.Set(elem)
+    return nil
+  case reflect.Interface:
+    if reflect.TypeOf(value).AssignableTo(dst.Type()) {
+      dst.Set(reflect.ValueOf(value))
+      return nil
+    }
+  case reflect.Struct:
+    switch v := value.(type) {
+    case *Document:
+      return d.decodeStruct(dst, v)
+    case map[string]interface{}:
+      return d.decodeStruct(dst, &Document{Fields: v})
+    case RID:
+      return d.decodeStruct(dst, &Document{RID: v})
+    case string:
+      if rid, err := ParseRID(v); err == nil {
+        return d.decodeStruct(dst, &Document{RID: rid})
+      }
+    }
+  case reflect.Slice:
+    items := reflect.ValueOf(value)
+    if items.Kind() != reflect.Slice {
+      break
+    }
+    slice := reflect.MakeSlice(dst.Type(), items.Len(), items.Len())
+    for i := 0; i < items.Len(); i++ {
+      if err := d.assign(slice.Index(i), items.Index(i).Interface()); err != nil {
+        return err
+      }
+    }
+    dst.Set(slice)
+    return nil
+  case reflect.Map:
+    entries, ok := value.(map[string]interface{})
+    if !ok || dst.Type().Key().Kind() != reflect.String {
+      break
+    }
+    m := reflect.MakeMapWithSize(dst.Type(), len(entries))
+    for key, entry := range entries {
+      elem := reflect.New(dst.Type().Elem()).Elem()
+      if err := d.assign(elem, entry); err != nil {
+        return err
+      }
+      m.SetMapIndex(reflect.ValueOf(key).Convert(dst.Type().Key()), elem)
+    }
+    dst.Set(m)
+    return nil
+  case reflect.String:
+    switch v := value.(type) {
+    case string:
+      dst.SetString(v)
+      return nil
+    case RID:
+      dst.SetString(v.String())
+      return nil
+    case json.Number:
+      dst.SetString(v.String())
+      return nil
+    }
+  case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+    if n, ok := value.(json.Number); ok {
+      i, err := n.Int64()
+      dst.SetInt(i)
+      return err
+    }
+  case reflect.Float32, reflect.Float64:
+    if n, ok := value.(json.Number); ok {
+      f, err := n.Float64()
+      dst.SetFloat(f)
+      return err
+    }
+  }
+
+  rv := reflect.ValueOf(value)
+  if rv.Type().ConvertibleTo(dst.Type()) && isNumber(rv.Kind()) == isNumber(dst.Kind()) {
+    dst.Set(rv.Convert(dst.Type()))
+    return nil
+  }
+
+  return fmt.Errorf("Could not convert %T to %s.", value, dst.Type())
+}
+
+func isNumber(kind reflect.Kind) bool {
+  switch kind {
+  case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+    reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+    reflect.Float32, reflect.Float64:
+    return true
+  }
+
+  return false
+}
+
+// lowerFirst turns the name of an untagged field into the property it maps
+// to (BirthDate maps to birthDate).
+func lowerFirst(s string) string {
+  if s == "" {
+    return s
+  }
+
+  return strings.ToLower(s[:1]) + s[1:]
+}
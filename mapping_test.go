@@ -0,0 +1,95 @@
+package goog
+
+import (
+  "fmt"
+  "net/http"
+  "testing"
+  "time"
+)
+
+type testPerson struct {
+  RID     RID    `goog:"@rid"`
+  Version int    `goog:"@version"`
+  Class   string `goog:"@class"`
+  Name    string
+  Age     int64 `goog:"age"`
+  Born    time.Time
+  Out     []RID        `goog:"out"`
+  Best    *testPerson  `goog:"best"`
+  Friends []testPerson `goog:"friends"`
+  Ignored string       `goog:"-"`
+}
+
+func TestQueryInto(t *testing.T) {
+  ts, db := connectTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    w.Write([]byte(`{"result": [
+      {"@type": "d", "@rid": "#11:0", "@version": 2, "@class": "Person", "@fieldTypes": "born=t",
+       "name": "Misa", "age": 40, "born": "1974-03-01 00:00:00", "out": ["#12:0"], "Ignored": "x",
+       "best": {"@type": "d", "@rid": "#11:1", "@version": 1, "@class": "Person", "name": "Beto"},
+       "friends": ["#11:2"]},
+      {"@type": "d", "@rid": "#11:1", "@version": 1, "@class": "Person", "name": "Beto", "best": "#11:0"}
+    ]}`))
+  })
+  defer ts.Close()
+
+  var people []testPerson
+
+  if err := db.QueryInto(&people, "select from Person", 0, "best:1"); err != nil {
+    t.Fatal(err)
+  }
+
+  if len(people) != 2 {
+    t.Fatalf("Expecting 2 people, got %d.", len(people))
+  }
+
+  misa := people[0]
+  if misa.RID != (RID{11, 0}) || misa.Version != 2 || misa.Class != "Person" || misa.Name != "Misa" || misa.Age != 40 || misa.Ignored != "" {
+    t.Fatalf("Unexpected person %#v.", misa)
+  }
+
+  if misa.Born.Year() != 1974 || len(misa.Out) != 1 || misa.Out[0] != (RID{12, 0}) {
+    t.Fatalf("Unexpected fields %#v.", misa)
+  }
+
+  if misa.Best == nil || misa.Best.Name != "Beto" || misa.Best.RID != (RID{11, 1}) {
+    t.Fatalf("Expanded link was not decoded: %#v.", misa.Best)
+  }
+
+  if len(misa.Friends) != 1 || misa.Friends[0].RID != (RID{11, 2}) || misa.Friends[0].Name != "" {
+    t.Fatalf("Unexpanded link should only carry its RID: %#v.", misa.Friends)
+  }
+
+  if people[1].Best == nil || people[1].Best.RID != (RID{11, 0}) {
+    t.Fatalf("Unexpected link %#v.", people[1].Best)
+  }
+
+  if err := db.QueryInto(people, "select from Person", 0, ""); err != ErrDestinationNotASlice {
+    t.Fatalf("Expecting ErrDestinationNotASlice, got %v.", err)
+  }
+}
+
+func TestDecodeInterface(t *testing.T) {
+  doc := &Document{Fields: map[string]interface{}{"name": "Misa", "best": RID{11, 1}}}
+
+  var any struct {
+    Name interface{} `goog:"name"`
+  }
+  if err := doc.Decode(&any); err != nil || any.Name != "Misa" {
+    t.Fatalf("Unexpected field %v (%v).", any.Name, err)
+  }
+
+  var stringer struct {
+    Best fmt.Stringer `goog:"best"`
+  }
+  if err := doc.Decode(&stringer); err != nil || stringer.Best.String() != "#11:1" {
+    t.Fatalf("Unexpected field %v (%v).", stringer.Best, err)
+  }
+
+  var wrong struct {
+    Name fmt.Stringer `goog:"name"`
+  }
+  if err := doc.Decode(&wrong); err == nil {
+    t.Fatalf("Expecting an error decoding a string into a fmt.Stringer.")
+  }
+}
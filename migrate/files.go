@@ -0,0 +1,131 @@
+package migrate
+
+import (
+  "fmt"
+  "io/ioutil"
+  "path/filepath"
+  "regexp"
+  "strconv"
+  "strings"
+)
+
+var (
+  // fileName matches migration files: <version>_<name>.up.sql and
+  // <version>_<name>.down.sql.
+  fileName = regexp.MustCompile(`^(\d+)_([\w-]+)\.(up|down)\.sql$`)
+
+  // missingClass matches the message of a query over a class that doesn't
+  // exist.
+  missingClass = regexp.MustCompile(`(?i)class .* not found`)
+
+  // schemaChange matches the statements OrientDB refuses to run within a
+  // transaction.
+  schemaChange = regexp.MustCompile(`(?i)^\s*(create|alter|drop|truncate)\s+(class|property|index|cluster|sequence)\b`)
+)
+
+// LoadDir adds the SQL migrations found in dir. Every migration is made of a
+// <version>_<name>.up.sql file and an optional <version>_<name>.down.sql one,
+// holding statements separated by semicolons. Lines starting with -- are
+// comments.
+func (m *Migrator) LoadDir(dir string) error {
+  files, err := ioutil.ReadDir(dir)
+  if err != nil {
+    return err
+  }
+
+  migrations := map[int64]*Migration{}
+  var versions []int64
+
+  for _, file := range files {
+    match := fileName.FindStringSubmatch(file.Name())
+    if file.IsDir() || match == nil {
+      continue
+    }
+
+    version, err := strconv.ParseInt(match[1], 10, 64)
+    if err != nil {
+      return err
+    }
+
+    buf, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+    if err != nil {
+      return err
+    }
+
+    migration, ok := migrations[version]
+    if !ok {
+      migration = &Migration{Version: version, Name: match[2]}
+      migrations[version] = migration
+      versions = append(versions, version)
+    }
+
+    if migration.Name != match[2] {
+      return fmt.Errorf("%w %d (%s and %s).", ErrDuplicateVersion, version, migration.Name, match[2])
+    }
+
+    if match[3] == "up" {
+      migration.Up = SplitStatements(string(buf))
+    } else {
+      migration.Down = SplitStatements(string(buf))
+    }
+  }
+
+  for _, version := range versions {
+    if len(migrations[version].Up) == 0 {
+      return fmt.Errorf("Migration %d %s has no up statements.", version, migrations[version].Name)
+    }
+    if err = m.Add(*migrations[version]); err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
+
+// SplitStatements splits a SQL script on the semicolons found outside quoted
+// strings, dropping comment lines and empty statements.
+func SplitStatements(script string) []string {
+  var statements []string
+  var current strings.Builder
+  var quote rune
+  var escaped bool
+
+  lines := strings.Split(script, "\n")
+  for i, line := range lines {
+    if quote == 0 && strings.HasPrefix(strings.TrimSpace(line), "--") {
+      continue
+    }
+
+    for _, r := range line {
+      switch {
+      case escaped:
+        escaped = false
+      case r == '\\' && quote != 0:
+        escaped = true
+      case quote != 0 && r == quote:
+        quote = 0
+      case quote == 0 && (r == '\'' || r == '"'):
+        quote = r
+      case quote == 0 && r == ';':
+        statements = appendStatement(statements, current.String())
+        current.Reset()
+        continue
+      }
+      current.WriteRune(r)
+    }
+
+    if i < len(lines)-1 {
+      current.WriteRune('\n')
+    }
+  }
+
+  return appendStatement(statements, current.String())
+}
+
+func appendStatement(statements []string, statement string) []string {
+  if statement = strings.TrimSpace(statement); statement != "" {
+    statements = append(statements, statement)
+  }
+
+  return statements
+}
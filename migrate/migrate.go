@@ -0,0 +1,302 @@
+// Package migrate applies versioned schema migrations to an OrientDB
+// database, keeping track of the ones already applied in a class of the
+// database itself.
+//
+// Migrations are either SQL scripts read from a directory (see LoadDir) or Go
+// functions added with Add. SQL migrations run through the batch endpoint
+// within a transaction, together with the statement recording them.
+package migrate
+
+import (
+  "errors"
+  "fmt"
+  "io"
+  "os"
+  "sort"
+  "strconv"
+  "time"
+
+  l4g "code.google.com/p/log4go"
+  "github.com/hiphoox/goog"
+)
+
+// DEFAULT_CLASS is the class applied migrations are recorded in unless
+// Migrator.Class says otherwise.
+const DEFAULT_CLASS = "SchemaMigration"
+
+var (
+  // ErrIrreversible is returned by Down when the last applied migration
+  // can't be reverted.
+  ErrIrreversible = errors.New(`Migration can't be reverted.`)
+
+  // ErrDuplicateVersion is returned when two migrations share a version.
+  ErrDuplicateVersion = errors.New(`Duplicate migration version`)
+
+  // ErrUnknownMigration is returned when the database records a migration
+  // that is not known to the Migrator.
+  ErrUnknownMigration = errors.New(`Applied migration is unknown`)
+)
+
+// Migration is a versioned change to a database. It is either written in SQL
+// (Up and Down hold its statements) or in Go (UpFunc and DownFunc). Down and
+// DownFunc may be left empty for migrations that can't be reverted.
+type Migration struct {
+  Version  int64
+  Name     string
+  Up       []string
+  Down     []string
+  UpFunc   func(db *goog.DataBase) error
+  DownFunc func(db *goog.DataBase) error
+}
+
+// Status tells whether a migration was applied, and when.
+type Status struct {
+  Migration
+  Applied   bool
+  AppliedAt time.Time
+}
+
+// Migrator applies migrations to a database.
+type Migrator struct {
+  // Class is the class applied migrations are recorded in.
+  Class string
+
+  // DryRun prints the statements that would run to Out instead of running
+  // them.
+  DryRun bool
+
+  // Out receives the statements printed on dry runs, os.Stdout by default.
+  Out io.Writer
+
+  db         *goog.DataBase
+  migrations []Migration
+}
+
+// New creates a Migrator for the given database.
+func New(db *goog.DataBase) *Migrator {
+  return &Migrator{
+    Class: DEFAULT_CLASS,
+    Out:   os.Stdout,
+    db:    db,
+  }
+}
+
+// Add adds migrations to the ones known to the migrator.
+func (m *Migrator) Add(migrations ...Migration) error {
+  for _, migration := range migrations {
+    for _, known := range m.migrations {
+      if known.Version == migration.Version {
+        return fmt.Errorf("%w %d.", ErrDuplicateVersion, migration.Version)
+      }
+    }
+    m.migrations = append(m.migrations, migration)
+  }
+
+  sort.Slice(m.migrations, func(i, j int) bool {
+    return m.migrations[i].Version < m.migrations[j].Version
+  })
+
+  return nil
+}
+
+// Status returns every known migration, in order, telling whether it was
+// applied.
+func (m *Migrator) Status() ([]Status, error) {
+  applied, err := m.applied()
+  if err != nil {
+    return nil, err
+  }
+
+  statuses := make([]Status, len(m.migrations))
+  for i, migration := range m.migrations {
+    statuses[i].Migration = migration
+    statuses[i].AppliedAt, statuses[i].Applied = applied[migration.Version]
+    delete(applied, migration.Version)
+  }
+
+  for version := range applied {
+    return nil, fmt.Errorf("%w %d.", ErrUnknownMigration, version)
+  }
+
+  return statuses, nil
+}
+
+// Up applies every pending migration, in order, stopping at the first one
+// that fails.
+func (m *Migrator) Up() error {
+  l4g.Trace("Inside Migrator.Up")
+
+  statuses, err := m.Status()
+  if err != nil {
+    return err
+  }
+
+  if err = m.ensureClass(); err != nil {
+    return err
+  }
+
+  for _, status := range statuses {
+    if status.Applied {
+      continue
+    }
+
+    l4g.Info("Applying migration %d %s", status.Version, status.Name)
+
+    if err = m.apply(status.Migration, status.Up, status.UpFunc, m.recordStatement(status.Migration)); err != nil {
+      return fmt.Errorf("Migration %d %s: %s", status.Version, status.Name, err)
+    }
+  }
+
+  return nil
+}
+
+// Down reverts the last applied migration.
+func (m *Migrator) Down() error {
+  l4g.Trace("Inside Migrator.Down")
+
+  statuses, err := m.Status()
+  if err != nil {
+    return err
+  }
+
+  for i := len(statuses) - 1; i >= 0; i-- {
+    status := statuses[i]
+    if !status.Applied {
+      continue
+    }
+
+    if len(status.Down) == 0 && status.DownFunc == nil {
+      return fmt.Errorf("Migration %d %s: %w", status.Version, status.Name, ErrIrreversible)
+    }
+
+    l4g.Info("Reverting migration %d %s", status.Version, status.Name)
+
+    forget := "delete from " + m.Class + " where version = " + strconv.FormatInt(status.Version, 10)
+    if err = m.apply(status.Migration, status.Down, status.DownFunc, forget); err != nil {
+      return fmt.Errorf("Migration %d %s: %s", status.Version, status.Name, err)
+    }
+
+    return nil
+  }
+
+  return nil
+}
+
+// apply runs the statements or the function of a migration followed by the
+// statement that records the change. SQL migrations run as a single
+// transactional script, unless they change the schema: OrientDB doesn't allow
+// that within a transaction, so their statements are then sent one by one, a
+// failure leaving the ones before it applied.
+func (m *Migrator) apply(migration Migration, statements []string, fn func(*goog.DataBase) error, record string) error {
+  if m.DryRun {
+    fmt.Fprintf(m.Out, "-- %d %s\n", migration.Version, migration.Name)
+    if fn != nil {
+      fmt.Fprintf(m.Out, "-- (Go function)\n")
+    }
+    for _, statement := range statements {
+      fmt.Fprintf(m.Out, "%s;\n", statement)
+    }
+    fmt.Fprintf(m.Out, "%s;\n", record)
+    return nil
+  }
+
+  if fn != nil {
+    if err := fn(m.db); err != nil {
+      return err
+    }
+    _, err := m.db.Command(goog.SQL, record)
+    return err
+  }
+
+  if !changesSchema(statements) {
+    _, err := m.db.Script(append(append([]string{}, statements...), record)...)
+    return err
+  }
+
+  for _, statement := range append(append([]string{}, statements...), record) {
+    if _, err := m.db.Command(goog.SQL, statement); err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
+
+// changesSchema tells whether any of the statements changes the schema.
+func changesSchema(statements []string) bool {
+  for _, statement := range statements {
+    if schemaChange.MatchString(statement) {
+      return true
+    }
+  }
+
+  return false
+}
+
+// recordStatement returns the statement recording a migration as applied.
+func (m *Migrator) recordStatement(migration Migration) string {
+  return "insert into " + m.Class + " set version = " + strconv.FormatInt(migration.Version, 10) +
+    ", name = " + strconv.Quote(migration.Name) + ", appliedAt = sysdate()"
+}
+
+// applied reads the versions recorded in the database along with the time
+// they were applied. A missing class means nothing was applied yet.
+func (m *Migrator) applied() (map[int64]time.Time, error) {
+  applied := map[int64]time.Time{}
+
+  docs, err := m.db.Query("select from "+m.Class, 0, "")
+  if errors.Is(err, goog.ErrNotFound) || isMissingClass(err) {
+    return applied, nil
+  }
+  if err != nil {
+    return nil, err
+  }
+
+  for _, doc := range docs {
+    applied[doc.GetInt64("version")] = doc.GetTime("appliedAt")
+  }
+
+  return applied, nil
+}
+
+// ensureClass creates the class applied migrations are recorded in, unless it
+// already exists.
+func (m *Migrator) ensureClass() error {
+  schema := m.db.Schema()
+
+  _, err := schema.Class(m.Class)
+  if !errors.Is(err, goog.ErrNotFound) {
+    return err
+  }
+
+  statements := []string{
+    "create class " + m.Class,
+    "create property " + m.Class + ".version LONG",
+    "create property " + m.Class + ".name STRING",
+    "create property " + m.Class + ".appliedAt DATETIME",
+    "create index " + m.Class + ".version on " + m.Class + " (version) UNIQUE",
+  }
+
+  if m.DryRun {
+    for _, statement := range statements {
+      fmt.Fprintf(m.Out, "%s;\n", statement)
+    }
+    return nil
+  }
+
+  for _, statement := range statements {
+    if _, err = m.db.Command(goog.SQL, statement); err != nil {
+      return err
+    }
+  }
+
+  return nil
+}
+
+// isMissingClass tells whether a query failed because its class doesn't
+// exist, which OrientDB reports as a command error.
+func isMissingClass(err error) bool {
+  var cmdErr *goog.CommandError
+
+  return errors.As(err, &cmdErr) && missingClass.MatchString(cmdErr.Message)
+}
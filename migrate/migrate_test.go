@@ -0,0 +1,222 @@
+package migrate
+
+import (
+  "bytes"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io/ioutil"
+  "net/http"
+  "net/http/httptest"
+  "os"
+  "path/filepath"
+  "reflect"
+  "regexp"
+  "sort"
+  "strings"
+  "testing"
+
+  "github.com/hiphoox/goog"
+)
+
+// fakeServer records the statements it receives and keeps the applied
+// migrations the way OrientDB would.
+type fakeServer struct {
+  classCreated bool
+  applied      map[string]bool
+  statements   []string
+
+  // transactional holds the statements received in a transactional batch.
+  transactional []string
+}
+
+var versionPattern = regexp.MustCompile(`version = (\d+)`)
+
+func (f *fakeServer) run(statement string) {
+  f.statements = append(f.statements, statement)
+
+  switch {
+  case statement == "create class "+DEFAULT_CLASS:
+    f.classCreated = true
+  case strings.HasPrefix(statement, "insert into "+DEFAULT_CLASS):
+    f.applied[versionPattern.FindStringSubmatch(statement)[1]] = true
+  case strings.HasPrefix(statement, "delete from "+DEFAULT_CLASS):
+    delete(f.applied, versionPattern.FindStringSubmatch(statement)[1])
+  }
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  buf, _ := ioutil.ReadAll(r.Body)
+  w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+  switch {
+  case strings.HasPrefix(r.URL.Path, "/connect/"):
+    w.WriteHeader(http.StatusNoContent)
+    return
+  case strings.HasPrefix(r.URL.Path, "/class/"):
+    if !f.classCreated {
+      w.WriteHeader(http.StatusNotFound)
+      return
+    }
+    w.Write([]byte(`{"name": "` + DEFAULT_CLASS + `"}`))
+    return
+  case strings.HasPrefix(r.URL.Path, "/query/"):
+    if !f.classCreated {
+      w.WriteHeader(http.StatusInternalServerError)
+      w.Write([]byte(`{"errors": [{"code": 500, "reason": 500, "content": "com.orientechnologies.orient.core.exception.OCommandExecutionException: Class '` + DEFAULT_CLASS + `' was not found"}]}`))
+      return
+    }
+    var records []string
+    for version := range f.applied {
+      records = append(records, `{"@type": "d", "@rid": "#20:`+version+`", "@version": 1, "@fieldTypes": "version=l,appliedAt=t", "version": `+version+`, "appliedAt": "2014-08-09 18:55:33"}`)
+    }
+    sort.Strings(records)
+    w.Write([]byte(`{"result": [` + strings.Join(records, ", ") + `]}`))
+    return
+  case strings.HasPrefix(r.URL.Path, "/batch/"):
+    var batch struct {
+      Transaction bool
+      Operations  []struct{ Script []string }
+    }
+    json.Unmarshal(buf, &batch)
+    if !batch.Transaction {
+      w.WriteHeader(http.StatusBadRequest)
+      return
+    }
+    for _, statement := range batch.Operations[0].Script {
+      if strings.HasPrefix(statement, "create class") || strings.HasPrefix(statement, "create property") {
+        w.WriteHeader(http.StatusInternalServerError)
+        w.Write([]byte(`{"errors": [{"code": 500, "reason": 500, "content": "com.orientechnologies.orient.core.exception.OSchemaException: Cannot create class inside a transaction"}]}`))
+        return
+      }
+    }
+    for _, statement := range batch.Operations[0].Script {
+      f.transactional = append(f.transactional, statement)
+      f.run(statement)
+    }
+  default:
+    f.run(string(buf))
+  }
+
+  w.Write([]byte(`{"result": []}`))
+}
+
+func TestMigrator(t *testing.T) {
+  dir, err := ioutil.TempDir("", "migrations")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer os.RemoveAll(dir)
+
+  files := map[string]string{
+    "0001_people.up.sql":      "-- Defining model\ncreate class Person extends V;\ncreate property Person.name STRING;\n",
+    "0001_people.down.sql":    "drop class Person",
+    "0003_referrers.up.sql":   "create class Referrer extends E;\ncreate vertex Person set name = 'Misa; the first'",
+    "0003_referrers.down.sql": "delete vertex Person;\ndrop class Referrer;",
+    "0004_nicknames.up.sql":   "update Person set nick = name",
+    "0004_nicknames.down.sql": "update Person remove nick",
+    "README.md":               "not a migration",
+  }
+  for name, content := range files {
+    if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  f := &fakeServer{applied: map[string]bool{}}
+  ts := httptest.NewServer(f)
+  defer ts.Close()
+
+  db, err := goog.Connect(strings.TrimPrefix(ts.URL, goog.HTTP_PREFIX), "geneology", "root", "root")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  var ran bool
+  m := New(&db)
+  if err = m.LoadDir(dir); err != nil {
+    t.Fatal(err)
+  }
+  if err = m.Add(Migration{Version: 2, Name: "indexes", UpFunc: func(db *goog.DataBase) error {
+    ran = true
+    return db.Schema().CreateIndex("", "Person", []string{"name"}, goog.UNIQUE)
+  }}); err != nil {
+    t.Fatal(err)
+  }
+
+  if err = m.Add(Migration{Version: 3, Name: "again"}); !errors.Is(err, ErrDuplicateVersion) {
+    t.Fatalf("Expecting ErrDuplicateVersion, got %v.", err)
+  }
+
+  var out bytes.Buffer
+  m.DryRun, m.Out = true, &out
+  if err = m.Up(); err != nil {
+    t.Fatal(err)
+  }
+
+  if ran || len(f.statements) != 0 || !strings.Contains(out.String(), "-- 3 referrers\ncreate class Referrer extends E;\n") {
+    t.Fatalf("Dry run should only print statements:\n%s", out.String())
+  }
+
+  m.DryRun = false
+  if err = m.Up(); err != nil {
+    t.Fatal(err)
+  }
+
+  expected := []string{
+    "create class SchemaMigration",
+    "create property SchemaMigration.version LONG",
+    "create property SchemaMigration.name STRING",
+    "create property SchemaMigration.appliedAt DATETIME",
+    "create index SchemaMigration.version on SchemaMigration (version) UNIQUE",
+    "create class Person extends V",
+    "create property Person.name STRING",
+    `insert into SchemaMigration set version = 1, name = "people", appliedAt = sysdate()`,
+    "create index Person.name on Person (name) UNIQUE",
+    `insert into SchemaMigration set version = 2, name = "indexes", appliedAt = sysdate()`,
+    "create class Referrer extends E",
+    "create vertex Person set name = 'Misa; the first'",
+    `insert into SchemaMigration set version = 3, name = "referrers", appliedAt = sysdate()`,
+    "update Person set nick = name",
+    `insert into SchemaMigration set version = 4, name = "nicknames", appliedAt = sysdate()`,
+  }
+  if !ran || !reflect.DeepEqual(f.statements, expected) {
+    t.Fatalf("Unexpected statements:\n%s", strings.Join(f.statements, "\n"))
+  }
+
+  // Only the migration that leaves the schema alone runs as a transaction.
+  if !reflect.DeepEqual(f.transactional, expected[len(expected)-2:]) {
+    t.Fatalf("Unexpected transactional statements:\n%s", strings.Join(f.transactional, "\n"))
+  }
+
+  for i := 0; i < 2; i++ {
+    if err = m.Down(); err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  statuses, err := m.Status()
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  var summary []string
+  for _, status := range statuses {
+    summary = append(summary, fmt.Sprintf("%d %s %v %d", status.Version, status.Name, status.Applied, status.AppliedAt.Year()))
+  }
+  if strings.Join(summary, ", ") != "1 people true 2014, 2 indexes true 2014, 3 referrers false 1, 4 nicknames false 1" {
+    t.Fatalf("Unexpected status %v.", summary)
+  }
+
+  if err = m.Down(); !errors.Is(err, ErrIrreversible) {
+    t.Fatalf("Expecting ErrIrreversible, got %v.", err)
+  }
+}
+
+func TestSplitStatements(t *testing.T) {
+  statements := SplitStatements("-- comment\ncreate vertex Person set name = 'a;b', nick = \"it\\\"s;\";\n\n;update Person set age = 1")
+
+  if !reflect.DeepEqual(statements, []string{`create vertex Person set name = 'a;b', nick = "it\"s;"`, "update Person set age = 1"}) {
+    t.Fatalf("Unexpected statements %q.", statements)
+  }
+}
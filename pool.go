@@ -0,0 +1,343 @@
+package goog
+
+import (
+  "context"
+  "net/http"
+  "sync"
+  "time"
+
+  l4g "code.google.com/p/log4go"
+  "github.com/hiphoox/goog/rest"
+)
+
+// PoolConfig sets the limits of a Pool.
+type PoolConfig struct {
+  // MinSessions are opened when the pool is created and kept open while
+  // idle.
+  MinSessions int
+
+  // MaxSessions is the most sessions open at once; Acquire waits for one
+  // to be released beyond that. Defaults to the PoolSize of the Config, or
+  // else 10.
+  MaxSessions int
+
+  // IdleTimeout closes sessions left idle for longer, zero keeps them open.
+  IdleTimeout time.Duration
+
+  // HealthCheckInterval is how long a session can stay idle before it is
+  // pinged again when acquired. Defaults to a minute.
+  HealthCheckInterval time.Duration
+}
+
+// PoolStats describes the state of a Pool.
+type PoolStats struct {
+  Open         int
+  Idle         int
+  InUse        int
+  Created      uint64
+  Discarded    uint64
+  WaitCount    uint64
+  WaitDuration time.Duration
+}
+
+// Pool keeps sessions with a database open so that goroutines can share them,
+// each session being used by one goroutine at a time. Every session of the
+// pool goes through the same http.Transport, sharing its keep-alive
+// connections.
+type Pool struct {
+  database  Config
+  config    PoolConfig
+  transport http.RoundTripper
+  slots     chan struct{}
+  done      chan struct{}
+
+  mu     sync.Mutex
+  idle   []idleSession
+  inUse  map[*DataBase]bool
+  stats  PoolStats
+  closed bool
+}
+
+type idleSession struct {
+  db    *DataBase
+  since time.Time
+}
+
+// NewPool creates a pool of sessions with the given database and opens its
+// first MinSessions sessions.
+func NewPool(server, database_name, login, password string, config PoolConfig) (*Pool, error) {
+  l4g.Trace("Inside NewPool")
+
+  return NewPoolWithConfig(Config{Server: server, Database: database_name, Login: login, Password: password}, config)
+}
+
+// NewPoolWithConfig creates a pool of sessions with the database described by
+// database and opens its first MinSessions sessions.
+func NewPoolWithConfig(database Config, config PoolConfig) (*Pool, error) {
+  l4g.Trace("Inside NewPoolWithConfig")
+
+  if config.MaxSessions <= 0 {
+    config.MaxSessions = database.PoolSize
+  }
+
+  if config.MaxSessions <= 0 {
+    config.MaxSessions = 10
+  }
+  if config.MinSessions > config.MaxSessions {
+    config.MinSessions = config.MaxSessions
+  }
+  if config.HealthCheckInterval <= 0 {
+    config.HealthCheckInterval = time.Minute
+  }
+
+  // Every session goes through the same transport, built once from the
+  // options of the database.
+  options, err := rest.NewOptions(append([]rest.ClientOption{
+    rest.WithMaxIdleConns(config.MaxSessions),
+    rest.WithIdleConnTimeout(config.IdleTimeout),
+  }, database.Options...)...)
+  if err != nil {
+    return nil, err
+  }
+
+  transport := options.RoundTripper()
+  database.Options = append(database.Options[:len(database.Options):len(database.Options)], rest.WithTransport(transport))
+
+  p := &Pool{
+    database:  database,
+    config:    config,
+    transport: transport,
+    slots:     make(chan struct{}, config.MaxSessions),
+    done:      make(chan struct{}),
+    inUse:     map[*DataBase]bool{},
+  }
+
+  for i := 0; i < config.MinSessions; i++ {
+    db, err := p.open(context.Background())
+    if err != nil {
+      p.Close()
+      return nil, err
+    }
+    p.idle = append(p.idle, idleSession{db, time.Now()})
+  }
+
+  if config.IdleTimeout > 0 {
+    go p.reap()
+  }
+
+  return p, nil
+}
+
+// Acquire returns a session for the exclusive use of the caller, who must
+// Release it when done. It waits for a session to be released when
+// MaxSessions are in use, until ctx is done or the pool is closed.
+func (p *Pool) Acquire(ctx context.Context) (*DataBase, error) {
+  l4g.Trace("Inside Pool.Acquire")
+
+  select {
+  case <-p.done:
+    return nil, ErrPoolClosed
+  default:
+  }
+
+  select {
+  case p.slots <- struct{}{}:
+  default:
+    start := time.Now()
+    var err error
+
+    select {
+    case p.slots <- struct{}{}:
+    case <-p.done:
+      err = ErrPoolClosed
+    case <-ctx.Done():
+      err = ctx.Err()
+    }
+
+    p.mu.Lock()
+    p.stats.WaitCount++
+    p.stats.WaitDuration += time.Since(start)
+    p.mu.Unlock()
+
+    if err != nil {
+      return nil, err
+    }
+  }
+
+  db, err := p.take(ctx)
+  if err != nil {
+    <-p.slots
+    return nil, err
+  }
+
+  return db, nil
+}
+
+// Release gives a session acquired from the pool back.
+func (p *Pool) Release(db *DataBase) {
+  p.mu.Lock()
+
+  if !p.inUse[db] {
+    p.mu.Unlock()
+    return
+  }
+  delete(p.inUse, db)
+
+  closed := p.closed
+  if !closed {
+    p.idle = append(p.idle, idleSession{db, time.Now()})
+  }
+
+  p.mu.Unlock()
+  <-p.slots
+
+  if closed {
+    p.discard(db)
+  }
+}
+
+// Stats returns the current state of the pool.
+func (p *Pool) Stats() PoolStats {
+  p.mu.Lock()
+  defer p.mu.Unlock()
+
+  stats := p.stats
+  stats.Idle = len(p.idle)
+  stats.InUse = len(p.inUse)
+  stats.Open = stats.Idle + stats.InUse
+
+  return stats
+}
+
+// Close closes the idle sessions of the pool, and the ones in use as they are
+// released. Acquire fails with ErrPoolClosed afterwards.
+func (p *Pool) Close() error {
+  l4g.Trace("Inside Pool.Close")
+
+  p.mu.Lock()
+  if p.closed {
+    p.mu.Unlock()
+    return nil
+  }
+  p.closed = true
+  idle := p.idle
+  p.idle = nil
+  p.mu.Unlock()
+
+  close(p.done)
+
+  for _, session := range idle {
+    p.discard(session.db)
+  }
+
+  if transport, ok := p.transport.(interface{ CloseIdleConnections() }); ok {
+    transport.CloseIdleConnections()
+  }
+
+  return nil
+}
+
+// take hands out an idle session, pinging it first if it has been idle for a
+// while, or opens a new one.
+func (p *Pool) take(ctx context.Context) (*DataBase, error) {
+  for {
+    p.mu.Lock()
+    if p.closed {
+      p.mu.Unlock()
+      return nil, ErrPoolClosed
+    }
+
+    n := len(p.idle)
+    if n == 0 {
+      p.mu.Unlock()
+      break
+    }
+
+    session := p.idle[n-1]
+    p.idle = p.idle[:n-1]
+    p.mu.Unlock()
+
+    if time.Since(session.since) >= p.config.HealthCheckInterval {
+      if err := session.db.PingContext(ctx); err != nil {
+        l4g.Trace("Discarding unhealthy session: %s", err)
+        p.discard(session.db)
+        continue
+      }
+    }
+
+    p.mu.Lock()
+    p.inUse[session.db] = true
+    p.mu.Unlock()
+
+    return session.db, nil
+  }
+
+  db, err := p.open(ctx)
+  if err != nil {
+    return nil, err
+  }
+
+  p.mu.Lock()
+  p.inUse[db] = true
+  p.mu.Unlock()
+
+  return db, nil
+}
+
+func (p *Pool) open(ctx context.Context) (*DataBase, error) {
+  db, err := dial(ctx, p.database)
+  if err != nil {
+    return nil, err
+  }
+
+  p.mu.Lock()
+  p.stats.Created++
+  p.mu.Unlock()
+
+  return &db, nil
+}
+
+func (p *Pool) discard(db *DataBase) {
+  p.mu.Lock()
+  p.stats.Discarded++
+  p.mu.Unlock()
+
+  if err := db.Close(); err != nil {
+    l4g.Trace("Could not close session: %s", err)
+  }
+}
+
+// reap closes the sessions left idle for longer than IdleTimeout, keeping
+// MinSessions open.
+func (p *Pool) reap() {
+  ticker := time.NewTicker(p.config.IdleTimeout / 2)
+  defer ticker.Stop()
+
+  for {
+    select {
+    case <-p.done:
+      return
+    case <-ticker.C:
+    }
+
+    var expired []*DataBase
+
+    p.mu.Lock()
+    open := len(p.idle) + len(p.inUse)
+    kept := p.idle[:0]
+    for _, session := range p.idle {
+      if open > p.config.MinSessions && time.Since(session.since) > p.config.IdleTimeout {
+        expired = append(expired, session.db)
+        open--
+        continue
+      }
+      kept = append(kept, session)
+    }
+    p.idle = kept
+    p.mu.Unlock()
+
+    for _, db := range expired {
+      p.discard(db)
+    }
+  }
+}
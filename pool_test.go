@@ -0,0 +1,222 @@
+package goog
+
+import (
+  "context"
+  "errors"
+  "fmt"
+  "net/http"
+  "net/http/httptest"
+  "strings"
+  "sync"
+  "testing"
+  "time"
+)
+
+func TestPool(t *testing.T) {
+  var mu sync.Mutex
+  var connects, disconnects int
+
+  ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    mu.Lock()
+    defer mu.Unlock()
+
+    switch {
+    case strings.HasPrefix(r.URL.Path, CONNECT_URL):
+      connects++
+      http.SetCookie(w, &http.Cookie{Name: SESSION_COOKIE, Value: fmt.Sprintf("OS%d", connects), Path: "/"})
+      w.WriteHeader(http.StatusNoContent)
+    case r.URL.Path == DISCONNECT_URL:
+      disconnects++
+      w.WriteHeader(http.StatusUnauthorized)
+    default:
+      w.Header().Set("Content-Type", "application/json; charset=utf-8")
+      w.Write([]byte(`{"result": []}`))
+    }
+  }))
+  defer ts.Close()
+
+  pool, err := NewPool(strings.TrimPrefix(ts.URL, HTTP_PREFIX), database_name, login, password,
+    PoolConfig{MinSessions: 1, MaxSessions: 2})
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if stats := pool.Stats(); stats.Open != 1 || stats.Idle != 1 || connects != 1 {
+    t.Fatalf("Unexpected stats after creation %+v.", stats)
+  }
+
+  first, err := pool.Acquire(context.Background())
+  if err != nil {
+    t.Fatal(err)
+  }
+  second, err := pool.Acquire(context.Background())
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if first == second || first.GetToken() == second.GetToken() {
+    t.Fatalf("Expecting two sessions, got %q twice.", first.GetToken())
+  }
+
+  if _, err = first.Query("select from Person", 0, ""); err != nil {
+    t.Fatal(err)
+  }
+
+  // Both sessions are in use: the next caller waits until its context is done.
+  ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+  defer cancel()
+
+  if _, err = pool.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+    t.Fatalf("Expecting context.DeadlineExceeded, got %v.", err)
+  }
+
+  // ... or until a session is released.
+  acquired := make(chan *DataBase)
+  go func() {
+    db, _ := pool.Acquire(context.Background())
+    acquired <- db
+  }()
+
+  time.Sleep(10 * time.Millisecond)
+  pool.Release(second)
+
+  if db := <-acquired; db != second {
+    t.Fatal("Expecting the released session to be reused.")
+  }
+
+  stats := pool.Stats()
+  if stats.Open != 2 || stats.InUse != 2 || stats.Created != 2 || stats.WaitCount != 2 {
+    t.Fatalf("Unexpected stats %+v.", stats)
+  }
+
+  pool.Release(first)
+  pool.Release(second)
+  pool.Release(second)
+
+  if stats = pool.Stats(); stats.Idle != 2 || stats.InUse != 0 {
+    t.Fatalf("Unexpected stats after release %+v.", stats)
+  }
+
+  pool.Close()
+
+  if _, err = pool.Acquire(context.Background()); !errors.Is(err, ErrPoolClosed) {
+    t.Fatalf("Expecting ErrPoolClosed, got %v.", err)
+  }
+
+  if disconnects != 2 {
+    t.Fatalf("Unexpected %d disconnects.", disconnects)
+  }
+}
+
+func TestPoolHealthCheck(t *testing.T) {
+  var mu sync.Mutex
+  var connects int
+  healthy := true
+
+  ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    mu.Lock()
+    defer mu.Unlock()
+
+    switch {
+    case strings.HasPrefix(r.URL.Path, CONNECT_URL):
+      if !healthy {
+        healthy = true
+        w.WriteHeader(http.StatusServiceUnavailable)
+        return
+      }
+      connects++
+      http.SetCookie(w, &http.Cookie{Name: SESSION_COOKIE, Value: fmt.Sprintf("OS%d", connects), Path: "/"})
+      w.WriteHeader(http.StatusNoContent)
+    default:
+      w.WriteHeader(http.StatusUnauthorized)
+    }
+  }))
+  defer ts.Close()
+
+  pool, err := NewPool(strings.TrimPrefix(ts.URL, HTTP_PREFIX), database_name, login, password,
+    PoolConfig{MinSessions: 1, HealthCheckInterval: time.Nanosecond, IdleTimeout: 10 * time.Millisecond})
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer pool.Close()
+
+  // The idle session fails its ping and is replaced.
+  mu.Lock()
+  healthy = false
+  mu.Unlock()
+
+  db, err := pool.Acquire(context.Background())
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if token := db.GetToken(); token != "OS2" {
+    t.Fatalf("Expecting a new session, got %q.", token)
+  }
+
+  if stats := pool.Stats(); stats.Discarded != 1 || stats.Created != 2 {
+    t.Fatalf("Unexpected stats %+v.", stats)
+  }
+
+  // Sessions idle for too long are closed, down to MinSessions.
+  other, err := pool.Acquire(context.Background())
+  if err != nil {
+    t.Fatal(err)
+  }
+  pool.Release(db)
+  pool.Release(other)
+
+  time.Sleep(50 * time.Millisecond)
+
+  if stats := pool.Stats(); stats.Open != 1 || stats.Discarded != 2 {
+    t.Fatalf("Unexpected stats after idle timeout %+v.", stats)
+  }
+}
+
+func TestPoolClose(t *testing.T) {
+  ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    if strings.HasPrefix(r.URL.Path, CONNECT_URL) {
+      http.SetCookie(w, &http.Cookie{Name: SESSION_COOKIE, Value: "OS1", Path: "/"})
+      w.WriteHeader(http.StatusNoContent)
+      return
+    }
+    w.WriteHeader(http.StatusUnauthorized)
+  }))
+  defer ts.Close()
+
+  pool, err := NewPool(strings.TrimPrefix(ts.URL, HTTP_PREFIX), database_name, login, password,
+    PoolConfig{MaxSessions: 1})
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  db, err := pool.Acquire(context.Background())
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  // Callers waiting for a session give up as soon as the pool is closed.
+  failed := make(chan error)
+  go func() {
+    _, err := pool.Acquire(context.Background())
+    failed <- err
+  }()
+
+  time.Sleep(10 * time.Millisecond)
+  pool.Close()
+
+  select {
+  case err = <-failed:
+    if !errors.Is(err, ErrPoolClosed) {
+      t.Fatalf("Expecting ErrPoolClosed, got %v.", err)
+    }
+  case <-time.After(time.Second):
+    t.Fatal("Acquire kept waiting on a closed pool.")
+  }
+
+  pool.Release(db)
+
+  if _, err = pool.Acquire(context.Background()); !errors.Is(err, ErrPoolClosed) {
+    t.Fatalf("Expecting ErrPoolClosed, got %v.", err)
+  }
+}
@@ -0,0 +1,129 @@
+package goog
+
+import (
+  "context"
+  "encoding/json"
+  "net/url"
+  "strconv"
+
+  l4g "code.google.com/p/log4go"
+)
+
+// result is the envelope OrientDB wraps around every set of records it
+// returns: {"result": [...]}.
+type result struct {
+  Result []*Document `json:"result"`
+}
+
+// Query runs an idempotent SQL statement (usually a SELECT) and returns the
+// records found. A limit lower than one returns every record, fetchPlan (see
+// FetchPlan) may be left empty to use the one given by Config, or else the
+// server's default.
+func (db *DataBase) Query(sql string, limit int, fetchPlan FetchPlan) ([]*Document, error) {
+  l4g.Trace("Inside Query")
+
+  return db.QueryContext(context.Background(), sql, limit, fetchPlan)
+}
+
+// QueryContext is like Query, the request being canceled when ctx is done.
+func (db *DataBase) QueryContext(ctx context.Context, sql string, limit int, fetchPlan FetchPlan) ([]*Document, error) {
+  l4g.Trace("Inside QueryContext")
+  var res result
+
+  if fetchPlan == "" {
+    fetchPlan = db.fetchPlan
+  }
+
+  if err := db.get(ctx, &res, queryPath(db.name, sql, limit, fetchPlan), nil); err != nil {
+    return nil, err
+  }
+
+  resolveLinks(res.Result...)
+
+  return res.Result, nil
+}
+
+// queryPath builds /query/<db>/sql/<text>/<limit>[/<fetchPlan>].
+func queryPath(database_name, sql string, limit int, fetchPlan FetchPlan) string {
+  if limit < 1 {
+    limit = -1
+  }
+
+  path := QUERY_URL + database_name + "/sql/" + url.PathEscape(sql) + "/" + strconv.Itoa(limit)
+  if fetchPlan != "" {
+    path += "/" + url.PathEscape(fetchPlan.String())
+  }
+
+  return path
+}
+
+// Languages understood by the command endpoint.
+const (
+  SQL     = "sql"
+  GREMLIN = "gremlin"
+  SCRIPT  = "script"
+)
+
+// Params holds named parameters for Command, referenced in the statement as
+// :name.
+type Params map[string]interface{}
+
+// commandRequest is the JSON body sent to /command when the statement carries
+// parameters.
+type commandRequest struct {
+  Command    string      `json:"command"`
+  Parameters interface{} `json:"parameters,omitempty"`
+}
+
+// Command executes a non-idempotent statement (CREATE, UPDATE, DELETE...)
+// written in the given language and returns the records the server answers
+// with. Parameters are positional (?) unless a single Params value is given,
+// in which case they are named (:name).
+func (db *DataBase) Command(language, text string, params ...interface{}) ([]*Document, error) {
+  l4g.Trace("Inside Command")
+
+  return db.CommandContext(context.Background(), language, text, params...)
+}
+
+// CommandContext is like Command, the request being canceled when ctx is done.
+// Commands are sent with POST, so a rest.RetryPolicy only retries them when
+// ctx was marked with rest.Idempotent().
+func (db *DataBase) CommandContext(ctx context.Context, language, text string, params ...interface{}) ([]*Document, error) {
+  l4g.Trace("Inside CommandContext")
+  var res result
+
+  body, err := commandBody(language, text, params)
+  if err != nil {
+    return nil, err
+  }
+
+  err = db.postRaw(ctx, &res, COMMAND_URL+db.name+"/"+language, body)
+
+  return res.Result, err
+}
+
+// commandBody validates the language and encodes the statement, as plain text
+// when there are no parameters or as a commandRequest otherwise.
+func commandBody(language, text string, params []interface{}) ([]byte, error) {
+  switch language {
+  case SQL, GREMLIN, SCRIPT:
+  default:
+    return nil, ErrUnknownLanguage
+  }
+
+  if len(params) == 0 {
+    return []byte(text), nil
+  }
+
+  req := commandRequest{Command: text, Parameters: params}
+  if len(params) == 1 {
+    switch named := params[0].(type) {
+    case Params:
+      req.Parameters = named
+    case map[string]interface{}:
+      req.Parameters = named
+    }
+  }
+
+  return json.Marshal(req)
+}
@@ -0,0 +1,103 @@
+package goog
+
+import (
+  "context"
+  "errors"
+  "io/ioutil"
+  "net/http"
+  "testing"
+  "time"
+)
+
+func TestQuery(t *testing.T) {
+  var path string
+
+  ts, db := connectTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+    path = r.URL.Path
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    w.Write([]byte(`{"result": [{"@type": "d", "@rid": "#11:0", "@version": 1, "name": "Misa"}, {"@type": "d", "@rid": "#11:1", "@version": 1, "name": "Beto"}]}`))
+  })
+  defer ts.Close()
+
+  records, err := db.Query("select from Person", 0, "*:1")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if path != "/query/geneology/sql/select from Person/-1/*:1" {
+    t.Fatalf("Unexpected path %q.", path)
+  }
+
+  if len(records) != 2 || records[1].GetString("name") != "Beto" {
+    t.Fatalf("Unexpected records %v.", records)
+  }
+}
+
+func TestQueryPath(t *testing.T) {
+  if p := queryPath("db", "select from V where name = 'a/b'", 10, ""); p != "/query/db/sql/select%20from%20V%20where%20name%20=%20%27a%2Fb%27/10" {
+    t.Fatalf("Unexpected path %q.", p)
+  }
+}
+
+func TestCommand(t *testing.T) {
+  var path, body string
+
+  ts, db := connectTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+    buf, _ := ioutil.ReadAll(r.Body)
+    path, body = r.URL.Path, string(buf)
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    w.Write([]byte(`{"result": [{"@type": "d", "@rid": "#11:2", "@version": 1, "@class": "Person", "name": "Nor"}]}`))
+  })
+  defer ts.Close()
+
+  records, err := db.Command(SQL, "create vertex Person set name = 'Nor'")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if path != "/command/geneology/sql" || body != "create vertex Person set name = 'Nor'" {
+    t.Fatalf("Unexpected request %q %q.", path, body)
+  }
+
+  if len(records) != 1 || records[0].RID.String() != "#11:2" || records[0].Class != "Person" {
+    t.Fatalf("Unexpected records %v.", records)
+  }
+
+  if _, err = db.Command(SQL, "update Person set name = ? where name = ?", "Nora", "Nor"); err != nil {
+    t.Fatal(err)
+  }
+
+  if body != `{"command":"update Person set name = ? where name = ?","parameters":["Nora","Nor"]}` {
+    t.Fatalf("Unexpected positional body %q.", body)
+  }
+
+  if _, err = db.Command(SQL, "delete vertex Person where name = :name", Params{"name": "Nora"}); err != nil {
+    t.Fatal(err)
+  }
+
+  if body != `{"command":"delete vertex Person where name = :name","parameters":{"name":"Nora"}}` {
+    t.Fatalf("Unexpected named body %q.", body)
+  }
+
+  if _, err = db.Command("cobol", "MOVE 1 TO X"); err != ErrUnknownLanguage {
+    t.Fatalf("Expecting ErrUnknownLanguage, got %v.", err)
+  }
+}
+
+func TestQueryContext(t *testing.T) {
+  ts, db := connectTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+    <-r.Context().Done()
+  })
+  defer ts.Close()
+
+  ctx, cancel := context.WithCancel(context.Background())
+  time.AfterFunc(20*time.Millisecond, cancel)
+
+  if _, err := db.QueryContext(ctx, "traverse out() from #11:0", 0, ""); !errors.Is(err, context.Canceled) {
+    t.Fatalf("Expecting context.Canceled, got %v.", err)
+  }
+
+  if _, err := db.CommandContext(ctx, SQL, "delete vertex Person"); !errors.Is(err, context.Canceled) {
+    t.Fatalf("Expecting context.Canceled, got %v.", err)
+  }
+}
@@ -2,6 +2,7 @@ package rest
 
 import (
 	"errors"
+	"fmt"
 )
 
 var (
@@ -17,7 +18,41 @@ var (
 	// converted to the expected datatype.
 	ErrCouldNotConvert = errors.New(`Could not convert response %s to %s.`)
 
+	// ErrInvalidCABundle is returned when a CA bundle holds no PEM encoded
+	// certificate.
+	ErrInvalidCABundle = errors.New(`No certificate found in CA bundle %s.`)
+
+	// ErrInvalidProxy is returned when a proxy URL can't be parsed.
+	ErrInvalidProxy = errors.New(`Expecting a valid proxy URL: %s.`)
+
 	// ErrDestinationNotAPointer is returned when attemping to provide a
 	// destination that is not a pointer.
 	ErrDestinationNotAPointer = errors.New(`Destination is not a pointer.`)
 )
+
+// maxErrorBody is the most of an error response body an HTTPError keeps.
+const maxErrorBody = 64 << 10
+
+// HTTPError is returned when the server answers with a 4xx or 5xx status code,
+// unless the destination is a *Response. Body holds the beginning of the
+// response body.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Method     string
+	URL        string
+	Body       []byte
+}
+
+func (e *HTTPError) Error() string {
+	excerpt := e.Body
+	if len(excerpt) > 200 {
+		excerpt = append(excerpt[:200:200], "..."...)
+	}
+
+	if len(excerpt) == 0 {
+		return fmt.Sprintf("%s %s: %s", e.Method, e.URL, e.Status)
+	}
+
+	return fmt.Sprintf("%s %s: %s: %s", e.Method, e.URL, e.Status, excerpt)
+}
@@ -0,0 +1,183 @@
+package rest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Options configures how a Client reaches the server. They are set with
+// ClientOption values given to New().
+type Options struct {
+	// Timeout limits the time a request may take, including reading the
+	// response body. Zero means no timeout.
+	Timeout time.Duration
+
+	// Transport is used as is when set: the TLS, proxy and idle connection
+	// options below only apply to the transport New() builds otherwise.
+	Transport http.RoundTripper
+
+	TLSConfig           *tls.Config
+	Proxy               func(*http.Request) (*url.URL, error)
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+
+	// Retry is the policy failed requests are retried with, none when nil.
+	Retry *RetryPolicy
+}
+
+// ClientOption sets one of the Options of a Client.
+type ClientOption func(*Options) error
+
+// NewOptions applies the given options, in order, to empty Options.
+func NewOptions(options ...ClientOption) (*Options, error) {
+	self := new(Options)
+
+	for _, option := range options {
+		if err := option(self); err != nil {
+			return nil, err
+		}
+	}
+
+	return self, nil
+}
+
+// RoundTripper returns the transport requests should go through: Transport
+// when set, a new *http.Transport when any transport option was given, or nil
+// to use http.DefaultTransport.
+func (self *Options) RoundTripper() http.RoundTripper {
+	if self.Transport != nil {
+		return self.Transport
+	}
+
+	if self.TLSConfig == nil && self.Proxy == nil && self.MaxIdleConnsPerHost == 0 && self.IdleConnTimeout == 0 {
+		return nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if self.TLSConfig != nil {
+		transport.TLSClientConfig = self.TLSConfig
+	}
+
+	if self.Proxy != nil {
+		transport.Proxy = self.Proxy
+	}
+
+	if self.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = self.MaxIdleConnsPerHost
+		if transport.MaxIdleConns < self.MaxIdleConnsPerHost {
+			transport.MaxIdleConns = self.MaxIdleConnsPerHost
+		}
+	}
+
+	if self.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = self.IdleConnTimeout
+	}
+
+	return transport
+}
+
+// WithTimeout limits the time each request may take.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(self *Options) error {
+		self.Timeout = timeout
+		return nil
+	}
+}
+
+// WithTransport makes requests go through the given RoundTripper.
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(self *Options) error {
+		self.Transport = transport
+		return nil
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used with https:// servers. The
+// configuration is copied, later changes to config are not seen.
+func WithTLSConfig(config *tls.Config) ClientOption {
+	return func(self *Options) error {
+		self.TLSConfig = config.Clone()
+		return nil
+	}
+}
+
+// WithCABundle trusts the certificate authorities in the given PEM file, in
+// place of the system ones, to verify the server's certificate.
+func WithCABundle(file string) ClientOption {
+	return func(self *Options) error {
+		buf, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(buf) {
+			return fmt.Errorf(ErrInvalidCABundle.Error(), file)
+		}
+
+		self.tlsConfig().RootCAs = pool
+
+		return nil
+	}
+}
+
+// WithClientCertificate presents the certificate in certFile, whose private
+// key is in keyFile (both PEM encoded), to servers asking for one.
+func WithClientCertificate(certFile, keyFile string) ClientOption {
+	return func(self *Options) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return err
+		}
+
+		config := self.tlsConfig()
+		config.Certificates = append(config.Certificates, cert)
+
+		return nil
+	}
+}
+
+// WithProxy sends requests through the proxy at the given URL, instead of the
+// one set by the HTTP_PROXY and HTTPS_PROXY environment variables.
+func WithProxy(proxy string) ClientOption {
+	return func(self *Options) error {
+		addr, err := url.Parse(proxy)
+		if err != nil {
+			return fmt.Errorf(ErrInvalidProxy.Error(), err.Error())
+		}
+
+		self.Proxy = http.ProxyURL(addr)
+
+		return nil
+	}
+}
+
+// WithMaxIdleConns keeps up to n idle connections to the server open, for
+// reuse by later requests.
+func WithMaxIdleConns(n int) ClientOption {
+	return func(self *Options) error {
+		self.MaxIdleConnsPerHost = n
+		return nil
+	}
+}
+
+// WithIdleConnTimeout closes connections left idle for longer than timeout.
+func WithIdleConnTimeout(timeout time.Duration) ClientOption {
+	return func(self *Options) error {
+		self.IdleConnTimeout = timeout
+		return nil
+	}
+}
+
+func (self *Options) tlsConfig() *tls.Config {
+	if self.TLSConfig == nil {
+		self.TLSConfig = new(tls.Config)
+	}
+	return self.TLSConfig
+}
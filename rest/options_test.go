@@ -0,0 +1,142 @@
+package rest
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCABundle(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"secure": true}`))
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "rest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bundle := filepath.Join(dir, "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err = ioutil.WriteFile(bundle, cert, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf map[string]interface{}
+
+	// The test server's certificate isn't trusted by default.
+	client, err := New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = client.Get(&buf, "/", nil); err == nil {
+		t.Fatalf("Expecting a certificate error.")
+	}
+
+	client, err = New(ts.URL, WithCABundle(bundle), WithMaxIdleConns(4), WithTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = client.Get(&buf, "/", nil); err != nil || buf["secure"] != true {
+		t.Fatalf("Test failed: %v.", err)
+	}
+
+	if _, err = New(ts.URL, WithCABundle(filepath.Join(dir, "missing.pem"))); err == nil {
+		t.Fatalf("Expecting an error for a missing bundle.")
+	}
+
+	empty := filepath.Join(dir, "empty.pem")
+	ioutil.WriteFile(empty, []byte("nothing here"), 0600)
+
+	if _, err = New(ts.URL, WithCABundle(empty)); err == nil || !strings.Contains(err.Error(), empty) {
+		t.Fatalf("Expecting an error naming the bundle, got %v.", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	client, err := New(ts.URL, WithTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf []byte
+
+	start := time.Now()
+	if err = client.Get(&buf, "/", nil); err == nil {
+		t.Fatalf("Expecting a timeout.")
+	}
+
+	if time.Since(start) > time.Second {
+		t.Fatalf("Timeout not applied.")
+	}
+}
+
+func TestProxy(t *testing.T) {
+	var host string
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.URL.Host
+		w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+
+	client, err := New("http://orientdb.example:2480", WithProxy(proxy.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf []byte
+	if err = client.Get(&buf, "/listDatabases", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if host != "orientdb.example:2480" || string(buf) != "proxied" {
+		t.Fatalf("Test failed.")
+	}
+
+	if _, err = New("http://orientdb.example:2480", WithProxy("://nowhere")); err == nil {
+		t.Fatalf("Expecting an invalid proxy error.")
+	}
+}
+
+func TestTransport(t *testing.T) {
+	transport := &http.Transport{}
+
+	options, err := NewOptions(WithTransport(transport), WithMaxIdleConns(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if options.RoundTripper() != transport {
+		t.Fatalf("Expecting the given transport to be used as is.")
+	}
+
+	if options, _ = NewOptions(WithTimeout(time.Second)); options.RoundTripper() != nil {
+		t.Fatalf("Expecting the default transport.")
+	}
+
+	if options, _ = NewOptions(WithMaxIdleConns(4)); options.RoundTripper().(*http.Transport).MaxIdleConnsPerHost != 4 {
+		t.Fatalf("Test failed.")
+	}
+}
@@ -4,6 +4,7 @@ package rest
 import (
   "bytes"
   "compress/gzip"
+  "context"
   "encoding/base64"
   "encoding/json"
   "fmt"
@@ -18,6 +19,8 @@ import (
   "path"
   "reflect"
   "strings"
+  "sync"
+  "time"
 )
 
 var enableDebug = true
@@ -58,10 +61,26 @@ type MultipartBody struct {
 // Client is useful in case you need to communicate with an API and you'd like
 // to use the same prefix for all of your requests or in scenarios where it
 // would be handy to keep a session cookie.
+//
+// A Client can be used by several goroutines at once, provided Header and
+// CookieJar are only changed through its methods once it is shared.
 type Client struct {
   Header    http.Header
   Prefix    string
   CookieJar *cookiejar.Jar
+
+  // Transport is used to make requests, http.DefaultTransport when nil.
+  // Clients sharing a Transport share its pool of keep-alive connections.
+  Transport http.RoundTripper
+
+  // Timeout limits the time a request may take, zero means no timeout.
+  Timeout time.Duration
+
+  // Retry is the policy failed requests are retried with, none when nil.
+  Retry *RetryPolicy
+
+  // mu guards Header and CookieJar.
+  mu sync.RWMutex
 }
 
 // DefaulClient is the default client used on top level functions like
@@ -78,16 +97,25 @@ func init() {
 
 // New creates a new client, in all following GET, POST, PUT and DELETE
 // requests given paths will be prefixed with the given client's prefix value.
-func New(prefix string) (*Client, error) {
+// Options set the timeout, transport, TLS and proxy settings of the client.
+func New(prefix string, options ...ClientOption) (*Client, error) {
   var err error
+  var opts *Options
 
   if _, err = url.Parse(prefix); err != nil {
     return nil, fmt.Errorf(ErrInvalidPrefix.Error(), err.Error())
   }
 
+  if opts, err = NewOptions(options...); err != nil {
+    return nil, err
+  }
+
   self := new(Client)
   self.Prefix = strings.TrimRight(prefix, "/") + "/"
   self.Header = http.Header{}
+  self.Transport = opts.RoundTripper()
+  self.Timeout = opts.Timeout
+  self.Retry = opts.Retry
 
   if self.CookieJar, err = cookiejar.New(nil); err != nil {
     return nil, err
@@ -104,15 +132,95 @@ func basicAuth(username, password string) string {
 
 // Sets the request's basic authorization header to be used in all requests.
 func (self *Client) SetBasicAuth(username string, password string) {
+  self.mu.Lock()
+  defer self.mu.Unlock()
+
   self.Header.Set("Authorization", "Basic "+basicAuth(username, password))
 }
 
+// ClearBasicAuth stops sending the authorization header SetBasicAuth set.
+func (self *Client) ClearBasicAuth() {
+  self.mu.Lock()
+  defer self.mu.Unlock()
+
+  self.Header.Del("Authorization")
+}
+
 // Sets the request's basic authorization header to be used in all requests.
 func (client *Client) GetHeader(header_name string) string {
+  client.mu.RLock()
+  defer client.mu.RUnlock()
+
   return client.Header.Get(header_name)
 }
 
-func (self *Client) newMultipartRequest(dst interface{}, method string, addr *url.URL, body *MultipartBody) error {
+// Cookie returns the named cookie the client sends along with its requests,
+// or nil when there is none.
+func (self *Client) Cookie(name string) *http.Cookie {
+  jar := self.jar()
+  if jar == nil {
+    return nil
+  }
+
+  addr, err := url.Parse(self.Prefix)
+  if err != nil {
+    return nil
+  }
+
+  for _, cookie := range jar.Cookies(addr) {
+    if cookie.Name == name {
+      return cookie
+    }
+  }
+
+  return nil
+}
+
+// SetCookie stores a cookie to be sent along with the client's requests.
+func (self *Client) SetCookie(cookie *http.Cookie) error {
+  var err error
+
+  self.mu.Lock()
+  defer self.mu.Unlock()
+
+  if self.CookieJar == nil {
+    if self.CookieJar, err = cookiejar.New(nil); err != nil {
+      return err
+    }
+  }
+
+  addr, err := url.Parse(self.Prefix)
+  if err != nil {
+    return err
+  }
+
+  self.CookieJar.SetCookies(addr, []*http.Cookie{cookie})
+
+  return nil
+}
+
+// ClearCookies forgets every cookie the client received.
+func (self *Client) ClearCookies() error {
+  jar, err := cookiejar.New(nil)
+  if err == nil {
+    self.mu.Lock()
+    self.CookieJar = jar
+    self.mu.Unlock()
+  }
+
+  return err
+}
+
+// jar returns the cookie jar requests are sent with. Requests already sent
+// keep the one they started with when ClearCookies replaces it.
+func (self *Client) jar() *cookiejar.Jar {
+  self.mu.RLock()
+  defer self.mu.RUnlock()
+
+  return self.CookieJar
+}
+
+func (self *Client) newMultipartRequest(ctx context.Context, dst interface{}, method string, addr *url.URL, body *MultipartBody) error {
   var res *http.Response
   var req *http.Request
 
@@ -122,7 +230,7 @@ func (self *Client) newMultipartRequest(dst interface{}, method string, addr *ur
     return ErrCouldNotCreateMultipart
   }
 
-  if req, err = http.NewRequest(method, addr.String(), body.buf); err != nil {
+  if req, err = http.NewRequestWithContext(ctx, method, addr.String(), body.buf); err != nil {
     return err
   }
 
@@ -139,24 +247,24 @@ func (self *Client) newMultipartRequest(dst interface{}, method string, addr *ur
   return nil
 }
 
-func (self *Client) newRequest(dst interface{}, method string, addr *url.URL, body *strings.Reader) error {
+func (self *Client) newRequest(ctx context.Context, dst interface{}, method string, addr *url.URL, body *strings.Reader) error {
   var res *http.Response
   var req *http.Request
 
   var err error
 
   if body == nil {
-    if req, err = http.NewRequest(method, addr.String(), nil); err != nil {
+    if req, err = http.NewRequestWithContext(ctx, method, addr.String(), nil); err != nil {
       return err
     }
   } else {
-    if req, err = http.NewRequest(method, addr.String(), body); err != nil {
+    if req, err = http.NewRequestWithContext(ctx, method, addr.String(), body); err != nil {
       return err
     }
   }
 
   switch method {
-  case "POST", "PUT":
+  case "POST", "PUT", "PATCH":
     if req.Header.Get("Content-Type") == "" {
       req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=UTF-8")
     }
@@ -177,6 +285,11 @@ func (self *Client) newRequest(dst interface{}, method string, addr *url.URL, bo
 // response body into the datatype given by dst (a pointer to a struct, map or
 // []byte array).
 func (self *Client) Put(dst interface{}, path string, data url.Values) error {
+  return self.PutContext(context.Background(), dst, path, data)
+}
+
+// PutContext is like Put, the request being canceled when ctx is done.
+func (self *Client) PutContext(ctx context.Context, dst interface{}, path string, data url.Values) error {
   var addr *url.URL
   var err error
   var body *strings.Reader
@@ -189,13 +302,18 @@ func (self *Client) Put(dst interface{}, path string, data url.Values) error {
     body = strings.NewReader(data.Encode())
   }
 
-  return self.newRequest(dst, "PUT", addr, body)
+  return self.newRequest(ctx, dst, "PUT", addr, body)
 }
 
 // Delete performs a HTTP DELETE request and, when complete, attempts to
 // convert the response body into the datatype given by dst (a pointer to a
 // struct, map or []byte array).
 func (self *Client) Delete(dst interface{}, path string, data url.Values) error {
+  return self.DeleteContext(context.Background(), dst, path, data)
+}
+
+// DeleteContext is like Delete, the request being canceled when ctx is done.
+func (self *Client) DeleteContext(ctx context.Context, dst interface{}, path string, data url.Values) error {
   var addr *url.URL
   var err error
   var body *strings.Reader
@@ -208,13 +326,19 @@ func (self *Client) Delete(dst interface{}, path string, data url.Values) error
     body = strings.NewReader(data.Encode())
   }
 
-  return self.newRequest(dst, "DELETE", addr, body)
+  return self.newRequest(ctx, dst, "DELETE", addr, body)
 }
 
 // PutMultipart performs a HTTP PUT multipart request and, when complete,
 // attempts to convert the response body into the datatype given by dst (a
 // pointer to a struct, map or []byte array).
 func (self *Client) PutMultipart(dst interface{}, uri string, data *MultipartBody) error {
+  return self.PutMultipartContext(context.Background(), dst, uri, data)
+}
+
+// PutMultipartContext is like PutMultipart, the request being canceled when
+// ctx is done.
+func (self *Client) PutMultipartContext(ctx context.Context, dst interface{}, uri string, data *MultipartBody) error {
   var addr *url.URL
   var err error
 
@@ -222,13 +346,19 @@ func (self *Client) PutMultipart(dst interface{}, uri string, data *MultipartBod
     return err
   }
 
-  return self.newMultipartRequest(dst, "PUT", addr, data)
+  return self.newMultipartRequest(ctx, dst, "PUT", addr, data)
 }
 
 // PostMultipart performs a HTTP POST multipart request and, when complete,
 // attempts to convert the response body into the datatype given by dst (a
 // pointer to a struct, map or []byte array).
 func (self *Client) PostMultipart(dst interface{}, uri string, data *MultipartBody) error {
+  return self.PostMultipartContext(context.Background(), dst, uri, data)
+}
+
+// PostMultipartContext is like PostMultipart, the request being canceled when
+// ctx is done.
+func (self *Client) PostMultipartContext(ctx context.Context, dst interface{}, uri string, data *MultipartBody) error {
   var addr *url.URL
   var err error
 
@@ -236,13 +366,18 @@ func (self *Client) PostMultipart(dst interface{}, uri string, data *MultipartBo
     return err
   }
 
-  return self.newMultipartRequest(dst, "POST", addr, data)
+  return self.newMultipartRequest(ctx, dst, "POST", addr, data)
 }
 
 // PostRaw performs a HTTP POST request with a custom body and, when complete,
 // attempts to convert the response body into the datatype given by dst (a
 // pointer to a struct, map or []byte array).
 func (self *Client) PostRaw(dst interface{}, path string, body []byte) error {
+  return self.PostRawContext(context.Background(), dst, path, body)
+}
+
+// PostRawContext is like PostRaw, the request being canceled when ctx is done.
+func (self *Client) PostRawContext(ctx context.Context, dst interface{}, path string, body []byte) error {
   var addr *url.URL
   var err error
   var bodyReader *strings.Reader
@@ -255,13 +390,67 @@ func (self *Client) PostRaw(dst interface{}, path string, body []byte) error {
     bodyReader = strings.NewReader(string(body))
   }
 
-  return self.newRequest(dst, "POST", addr, bodyReader)
+  return self.newRequest(ctx, dst, "POST", addr, bodyReader)
+}
+
+// PutRaw performs a HTTP PUT request with a custom body and, when complete,
+// attempts to convert the response body into the datatype given by dst (a
+// pointer to a struct, map or []byte array).
+func (self *Client) PutRaw(dst interface{}, path string, body []byte) error {
+  return self.PutRawContext(context.Background(), dst, path, body)
+}
+
+// PutRawContext is like PutRaw, the request being canceled when ctx is done.
+func (self *Client) PutRawContext(ctx context.Context, dst interface{}, path string, body []byte) error {
+  var addr *url.URL
+  var err error
+  var bodyReader *strings.Reader
+
+  if addr, err = url.Parse(self.Prefix + strings.TrimLeft(path, "/")); err != nil {
+    return err
+  }
+
+  if body != nil {
+    bodyReader = strings.NewReader(string(body))
+  }
+
+  return self.newRequest(ctx, dst, "PUT", addr, bodyReader)
+}
+
+// PatchRaw performs a HTTP PATCH request with a custom body and, when
+// complete, attempts to convert the response body into the datatype given by
+// dst (a pointer to a struct, map or []byte array).
+func (self *Client) PatchRaw(dst interface{}, path string, body []byte) error {
+  return self.PatchRawContext(context.Background(), dst, path, body)
+}
+
+// PatchRawContext is like PatchRaw, the request being canceled when ctx is
+// done.
+func (self *Client) PatchRawContext(ctx context.Context, dst interface{}, path string, body []byte) error {
+  var addr *url.URL
+  var err error
+  var bodyReader *strings.Reader
+
+  if addr, err = url.Parse(self.Prefix + strings.TrimLeft(path, "/")); err != nil {
+    return err
+  }
+
+  if body != nil {
+    bodyReader = strings.NewReader(string(body))
+  }
+
+  return self.newRequest(ctx, dst, "PATCH", addr, bodyReader)
 }
 
 // Post performs a HTTP POST request and, when complete, attempts to convert
 // the response body into the datatype given by dst (a pointer to a struct, map
 // or []byte array).
 func (self *Client) Post(dst interface{}, path string, data url.Values) error {
+  return self.PostContext(context.Background(), dst, path, data)
+}
+
+// PostContext is like Post, the request being canceled when ctx is done.
+func (self *Client) PostContext(ctx context.Context, dst interface{}, path string, data url.Values) error {
   var addr *url.URL
   var err error
   var body *strings.Reader
@@ -274,13 +463,18 @@ func (self *Client) Post(dst interface{}, path string, data url.Values) error {
     body = strings.NewReader(data.Encode())
   }
 
-  return self.newRequest(dst, "POST", addr, body)
+  return self.newRequest(ctx, dst, "POST", addr, body)
 }
 
 // Get performs a HTTP GET request and, when complete, attempts to convert the
 // response body into the datatype given by dst (a pointer to a struct, map or
 // []byte array).
 func (self *Client) Get(dst interface{}, path string, data url.Values) error {
+  return self.GetContext(context.Background(), dst, path, data)
+}
+
+// GetContext is like Get, the request being canceled when ctx is done.
+func (self *Client) GetContext(ctx context.Context, dst interface{}, path string, data url.Values) error {
   var addr *url.URL
   var err error
 
@@ -296,17 +490,23 @@ func (self *Client) Get(dst interface{}, path string, data url.Values) error {
     }
   }
 
-  return self.newRequest(dst, "GET", addr, nil)
+  return self.newRequest(ctx, dst, "GET", addr, nil)
 }
 
 // We don't need any GET vars
 // We also don't care about the response body
 func (client *Client) GetHeaders(path string) error {
+  return client.GetHeadersContext(context.Background(), path)
+}
+
+// GetHeadersContext is like GetHeaders, the request being canceled when ctx is
+// done.
+func (client *Client) GetHeadersContext(ctx context.Context, path string) error {
   // var buf []byte
-  err := client.Get(nil, path, nil)
-      if err.Error() == "EOF" {
-      err = nil
-    }
+  err := client.GetContext(ctx, nil, path, nil)
+  if err != nil && err.Error() == "EOF" {
+    err = nil
+  }
   return err
 }
 
@@ -355,9 +555,11 @@ func (self *Client) body(res *http.Response) (io.ReadCloser, error) {
   var err error
 
   if res.Header.Get("Content-Encoding") == "gzip" {
-    if body, err = gzip.NewReader(res.Body); err != nil {
+    var reader *gzip.Reader
+    if reader, err = gzip.NewReader(res.Body); err != nil {
       return nil, err
     }
+    body = gzipBody{reader, res.Body}
   } else {
     body = res.Body
   }
@@ -365,6 +567,17 @@ func (self *Client) body(res *http.Response) (io.ReadCloser, error) {
   return body, nil
 }
 
+// gzipBody closes the response body along with the reader decompressing it.
+type gzipBody struct {
+  *gzip.Reader
+  raw io.ReadCloser
+}
+
+func (b gzipBody) Close() error {
+  b.Reader.Close()
+  return b.raw.Close()
+}
+
 func fromBytes(dst reflect.Value, buf []byte) error {
   var err error
 
@@ -411,7 +624,21 @@ func fromBytes(dst reflect.Value, buf []byte) error {
   return fmt.Errorf(ErrCouldNotConvert.Error(), reflect.TypeOf(buf), dst.Type())
 }
 
+// maxDrainedBody is the most of an unread response body read so that its
+// connection can be reused; larger ones are closed along with it.
+const maxDrainedBody = 256 << 10
+
 func (self *Client) handleResponse(dst interface{}, res *http.Response) error {
+  handed := false
+
+  // Unless the caller reads it, the body is drained and closed here, so that
+  // the connection goes back to the pool.
+  defer func() {
+    if !handed {
+      io.Copy(ioutil.Discard, io.LimitReader(res.Body, maxDrainedBody))
+      res.Body.Close()
+    }
+  }()
 
   body, err := self.body(res)
 
@@ -419,6 +646,12 @@ func (self *Client) handleResponse(dst interface{}, res *http.Response) error {
     return err
   }
 
+  if res.StatusCode >= http.StatusBadRequest {
+    if _, ok := dst.(*Response); !ok {
+      return newHTTPError(res, body)
+    }
+  }
+
   if dst == nil {
     return nil
   }
@@ -456,6 +689,7 @@ func (self *Client) handleResponse(dst interface{}, res *http.Response) error {
 
     rv.Elem().Set(reflect.ValueOf(r))
   case ioReadCloserType:
+    handed = true
     rv.Elem().Set(reflect.ValueOf(body))
   case bytesBufferType:
     buf, err := ioutil.ReadAll(body)
@@ -499,48 +733,123 @@ func (self *Client) handleResponse(dst interface{}, res *http.Response) error {
   return nil
 }
 
+// newHTTPError reads the beginning of an error response into an HTTPError.
+func newHTTPError(res *http.Response, body io.ReadCloser) error {
+  defer res.Body.Close()
+
+  buf, err := ioutil.ReadAll(io.LimitReader(body, maxErrorBody))
+
+  if enableDebug == true {
+    log.Printf("Body:\n%s\n", string(buf))
+  }
+
+  if err != nil {
+    return err
+  }
+
+  e := &HTTPError{
+    StatusCode: res.StatusCode,
+    Status:     res.Status,
+    Body:       buf,
+  }
+
+  if res.Request != nil {
+    e.Method = res.Request.Method
+    e.URL = res.Request.URL.String()
+  }
+
+  return e
+}
+
 func (self *Client) do(req *http.Request) (*http.Response, error) {
   client := new(http.Client)
+  client.Transport = self.Transport
+  client.Timeout = self.Timeout
 
   // Adding cookie jar
-  if self.CookieJar != nil {
-    client.Jar = self.CookieJar
+  if jar := self.jar(); jar != nil {
+    client.Jar = jar
   }
 
   // Copying headers
+  self.mu.RLock()
   for k := range self.Header {
     req.Header.Set(k, self.Header.Get(k))
   }
+  self.mu.RUnlock()
 
   if req.Body == nil {
     req.Header.Del("Content-Type")
     req.Header.Del("Content-Length")
   }
 
-  res, err := client.Do(req)
+  attempts := 1
+  if self.Retry != nil && isIdempotent(req) {
+    attempts = self.Retry.MaxAttempts
+  }
 
-  if enableDebug == true {
+  for attempt := 1; ; attempt++ {
+    res, err := client.Do(req)
+
+    if enableDebug == true {
 
-    log.Printf("Fetching %v\n", req.URL.String())
+      log.Printf("Fetching %v\n", req.URL.String())
 
-    log.Printf("> %s %s", req.Method, req.Proto)
-    for k := range req.Header {
-      for kk := range req.Header[k] {
-        log.Printf("> %s: %s", k, req.Header[k][kk])
+      log.Printf("> %s %s", req.Method, req.Proto)
+      for k := range req.Header {
+        for kk := range req.Header[k] {
+          log.Printf("> %s: %s", k, req.Header[k][kk])
+        }
       }
-    }
 
-    log.Printf("< %s %s", res.Proto, res.Status)
-    for k := range res.Header {
-      for kk := range res.Header[k] {
-        log.Printf("< %s: %s", k, res.Header[k][kk])
+      if err != nil {
+        log.Printf("< %s", err)
+      } else {
+        log.Printf("< %s %s", res.Proto, res.Status)
+        for k := range res.Header {
+          for kk := range res.Header[k] {
+            log.Printf("< %s: %s", k, res.Header[k][kk])
+          }
+        }
       }
+
+      log.Printf("\n")
     }
 
-    log.Printf("\n")
-  }
+    if attempt >= attempts || req.Context().Err() != nil || !self.Retry.retryable(res, err) {
+      return res, err
+    }
+
+    // A body we can't read again can't be sent again.
+    if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+      return res, err
+    }
+
+    wait := self.Retry.backoff(attempt, res)
+
+    if res != nil {
+      io.Copy(ioutil.Discard, io.LimitReader(res.Body, maxErrorBody))
+      res.Body.Close()
+    }
 
-  return res, err
+    if enableDebug == true {
+      log.Printf("Retrying in %s (attempt %d of %d)\n", wait, attempt+1, attempts)
+    }
+
+    timer := time.NewTimer(wait)
+    select {
+    case <-timer.C:
+    case <-req.Context().Done():
+      timer.Stop()
+      return nil, req.Context().Err()
+    }
+
+    if req.GetBody != nil {
+      if req.Body, err = req.GetBody(); err != nil {
+        return nil, err
+      }
+    }
+  }
 }
 
 // Get performs a HTTP GET request using the default client and, when complete,
@@ -1,12 +1,17 @@
 package rest
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"path"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -205,6 +210,44 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestPutRaw(t *testing.T) {
+	var buf map[string]interface{}
+	var err error
+
+	err = client.PutRaw(&buf, "/document", []byte(`{"name": "Misa"}`))
+
+	if err != nil {
+		t.Fatalf("Failed test: %s\n", err.Error())
+	}
+
+	if buf["method"].(string) != "PUT" {
+		t.Fatalf("Test failed.")
+	}
+
+	if _, ok := buf["post"].(map[string]interface{})[`{"name": "Misa"}`]; !ok {
+		t.Fatalf("Test failed.")
+	}
+}
+
+func TestPatchRaw(t *testing.T) {
+	var buf map[string]interface{}
+	var err error
+
+	err = client.PatchRaw(&buf, "/document", []byte(`{"name": "Misa"}`))
+
+	if err != nil {
+		t.Fatalf("Failed test: %s\n", err.Error())
+	}
+
+	if buf["method"].(string) != "PATCH" {
+		t.Fatalf("Test failed.")
+	}
+
+	if _, ok := buf["post"].(map[string]interface{})[`{"name": "Misa"}`]; !ok {
+		t.Fatalf("Test failed.")
+	}
+}
+
 func TestPostMultipart(t *testing.T) {
 	fileRest, err := os.Open("rest.go")
 
@@ -408,3 +451,146 @@ func TestBasicAuth(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestHTTPError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"errors": [{"code": 404, "reason": 404, "content": "Record not found"}]}`))
+	}))
+	defer ts.Close()
+
+	client, err := New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf map[string]interface{}
+
+	err = client.Get(&buf, "/document/db/11:9", nil)
+
+	httpErr, ok := err.(*HTTPError)
+	if !ok {
+		t.Fatalf("Expecting an *HTTPError, got %v.", err)
+	}
+
+	if httpErr.StatusCode != 404 || httpErr.Method != "GET" || httpErr.URL != ts.URL+"/document/db/11:9" {
+		t.Fatalf("Test failed.")
+	}
+
+	if string(httpErr.Body) != `{"errors": [{"code": 404, "reason": 404, "content": "Record not found"}]}` {
+		t.Fatalf("Test failed.")
+	}
+
+	if err = client.GetHeaders("/connect/db"); err == nil {
+		t.Fatalf("Expecting an error without a destination.")
+	}
+
+	var res Response
+	if err = client.Get(&res, "/document/db/11:9", nil); err != nil || res.StatusCode != 404 {
+		t.Fatalf("A *Response destination should receive error responses.")
+	}
+}
+
+func TestCookies(t *testing.T) {
+	client, err := New("http://" + testServer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if client.Cookie("OSESSIONID") != nil {
+		t.Fatalf("Expecting no cookie.")
+	}
+
+	if err = client.SetCookie(&http.Cookie{Name: "OSESSIONID", Value: "OS1", Path: "/"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf map[string]interface{}
+	if err = client.Get(&buf, "/cookies", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if buf["header"].(map[string]interface{})["Cookie"].([]interface{})[0].(string) != "OSESSIONID=OS1" {
+		t.Fatalf("Test failed.")
+	}
+
+	if cookie := client.Cookie("OSESSIONID"); cookie == nil || cookie.Value != "OS1" {
+		t.Fatalf("Test failed.")
+	}
+
+	client.ClearCookies()
+
+	if client.Cookie("OSESSIONID") != nil {
+		t.Fatalf("Test failed.")
+	}
+}
+
+func TestContext(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	client, err := New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var buf map[string]interface{}
+
+	if err = client.GetContext(ctx, &buf, "/traverse", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expecting context.DeadlineExceeded, got %v.", err)
+	}
+
+	if err = client.PostRawContext(ctx, &buf, "/command", []byte("select")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expecting context.DeadlineExceeded, got %v.", err)
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	var connections int32
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/forbidden" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+		w.Write([]byte(`{"result": "a body nobody reads"}`))
+	}))
+	ts.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	client, err := New(ts.URL, WithTransport(&http.Transport{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err = client.GetHeaders("/connect"); err != nil {
+			t.Fatal(err)
+		}
+		if err = client.Delete(nil, "/document", nil); err != nil {
+			t.Fatal(err)
+		}
+		if err = client.GetHeaders("/forbidden"); err == nil {
+			t.Fatalf("Expecting an error.")
+		}
+	}
+
+	if n := atomic.LoadInt32(&connections); n != 1 {
+		t.Fatalf("Unread bodies should not use up connections, %d were opened.", n)
+	}
+}
@@ -0,0 +1,142 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy tells a Client how to send a request again when it fails with a
+// transient error: a network error such as a connection reset, or one of the
+// RetryableStatus status codes.
+//
+// Only idempotent requests are retried: GET, HEAD, OPTIONS, PUT and DELETE
+// ones, and those made with a context marked by Idempotent().
+type RetryPolicy struct {
+	// MaxAttempts is the most times a request is sent, the first one included.
+	MaxAttempts int
+
+	// The wait before attempt n is InitialBackoff * 2^(n-2), or what the
+	// server asked for with a Retry-After header if longer. Either way it
+	// never exceeds MaxBackoff, so that a server can't hold a client back
+	// longer than the policy allows; raise MaxBackoff to honour longer ones.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// Jitter is the fraction of each wait that is random, from 0 to 1, so that
+	// clients failing together don't retry together.
+	Jitter float64
+
+	RetryableStatus []int
+}
+
+// DefaultRetryPolicy is used by WithRetryPolicy() in place of the zero
+// MaxAttempts, InitialBackoff, MaxBackoff and RetryableStatus fields of a
+// policy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Jitter:         0.2,
+	RetryableStatus: []int{
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout,
+	},
+}
+
+// WithRetryPolicy retries requests that fail with a transient error as
+// described by policy.
+func WithRetryPolicy(policy RetryPolicy) ClientOption {
+	return func(self *Options) error {
+		if policy.MaxAttempts == 0 {
+			policy.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+		}
+		if policy.InitialBackoff == 0 {
+			policy.InitialBackoff = DefaultRetryPolicy.InitialBackoff
+		}
+		if policy.MaxBackoff == 0 {
+			policy.MaxBackoff = DefaultRetryPolicy.MaxBackoff
+		}
+		if policy.RetryableStatus == nil {
+			policy.RetryableStatus = DefaultRetryPolicy.RetryableStatus
+		}
+
+		self.Retry = &policy
+
+		return nil
+	}
+}
+
+type idempotentKey struct{}
+
+// Idempotent marks the requests made with the returned context as safe to
+// send again, whatever their method.
+func Idempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+
+	marked, _ := req.Context().Value(idempotentKey{}).(bool)
+
+	return marked
+}
+
+// retryable tells whether a request that got res or err is worth sending
+// again.
+func (self *RetryPolicy) retryable(res *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+
+		return errors.Is(err, syscall.ECONNRESET) ||
+			errors.Is(err, syscall.ECONNREFUSED) ||
+			errors.Is(err, io.EOF) ||
+			errors.Is(err, io.ErrUnexpectedEOF) ||
+			(errors.As(err, &netErr) && netErr.Timeout())
+	}
+
+	for _, status := range self.RetryableStatus {
+		if res.StatusCode == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backoff returns how long to wait after the given attempt failed with res.
+func (self *RetryPolicy) backoff(attempt int, res *http.Response) time.Duration {
+	wait := self.InitialBackoff << uint(attempt-1)
+	if wait > self.MaxBackoff || wait <= 0 {
+		wait = self.MaxBackoff
+	}
+
+	if self.Jitter > 0 {
+		wait -= time.Duration(rand.Float64() * self.Jitter * float64(wait))
+	}
+
+	if res != nil {
+		if seconds, err := strconv.Atoi(res.Header.Get("Retry-After")); err == nil {
+			if after := time.Duration(seconds) * time.Second; after > wait {
+				wait = after
+			}
+		}
+	}
+
+	if wait > self.MaxBackoff {
+		wait = self.MaxBackoff
+	}
+
+	return wait
+}
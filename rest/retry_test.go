@@ -0,0 +1,101 @@
+package rest
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	var mu sync.Mutex
+	var attempts int
+	var bodies []string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		attempts++
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+
+		if r.URL.Path == "/unavailable" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		switch attempts % 3 {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			// Drop the connection without answering.
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer ts.Close()
+
+	client, err := New(ts.URL, WithRetryPolicy(RetryPolicy{InitialBackoff: time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf []byte
+
+	if err = client.Get(&buf, "/query", nil); err != nil || string(buf) != "ok" || attempts != 3 {
+		t.Fatalf("Expecting success on the third attempt (%v, %d attempts).", err, attempts)
+	}
+
+	// POST isn't idempotent: the first 503 is final.
+	attempts = 0
+	if err = client.PostRaw(&buf, "/command", []byte("create vertex")); err == nil || attempts != 1 {
+		t.Fatalf("Expecting a single attempt (%v, %d attempts).", err, attempts)
+	}
+
+	// Unless marked otherwise, in which case the body is sent every time.
+	attempts, bodies = 0, nil
+	if err = client.PostRawContext(Idempotent(context.Background()), &buf, "/command", []byte("select")); err != nil || attempts != 3 {
+		t.Fatalf("Expecting success on the third attempt (%v, %d attempts).", err, attempts)
+	}
+
+	for _, body := range bodies {
+		if body != "select" {
+			t.Fatalf("Unexpected body %q.", body)
+		}
+	}
+
+	// Attempts are limited.
+	client.Retry.MaxAttempts = 2
+	attempts = 0
+	if err = client.Get(&buf, "/unavailable", nil); err == nil || attempts != 2 {
+		t.Fatalf("Expecting two attempts (%v, %d attempts).", err, attempts)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Jitter: 0.5}
+
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		wait := policy.backoff(attempt+1, nil)
+		if wait > max || wait < max/2 {
+			t.Fatalf("Unexpected wait %s after attempt %d.", wait, attempt+1)
+		}
+	}
+
+	res := &http.Response{Header: http.Header{"Retry-After": []string{"3"}}}
+	if wait := policy.backoff(1, res); wait != time.Second {
+		t.Fatalf("Retry-After should be capped by MaxBackoff, got %s.", wait)
+	}
+
+	policy.MaxBackoff = 5 * time.Second
+	if wait := policy.backoff(1, res); wait != 3*time.Second {
+		t.Fatalf("Expecting the wait the server asked for, got %s.", wait)
+	}
+}
@@ -0,0 +1,94 @@
+package goog
+
+import (
+  "encoding/json"
+  "fmt"
+  "strconv"
+  "strings"
+)
+
+// RID is an OrientDB record id, written #<cluster id>:<cluster position>.
+// Records that were not yet saved carry a temporary id with a negative
+// cluster id or position, like #-2:1.
+type RID struct {
+  ClusterID int
+  Position  int64
+}
+
+// ParseRID parses a record id written as #11:1 (the leading # is optional).
+func ParseRID(s string) (RID, error) {
+  var rid RID
+
+  parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(s), "#"), ":")
+  if len(parts) != 2 {
+    return rid, fmt.Errorf("%w %q.", ErrInvalidRID, s)
+  }
+
+  cluster, err := strconv.Atoi(parts[0])
+  if err != nil {
+    return rid, fmt.Errorf("%w %q.", ErrInvalidRID, s)
+  }
+
+  position, err := strconv.ParseInt(parts[1], 10, 64)
+  if err != nil {
+    return rid, fmt.Errorf("%w %q.", ErrInvalidRID, s)
+  }
+
+  rid.ClusterID = cluster
+  rid.Position = position
+
+  return rid, nil
+}
+
+// String returns the record id as OrientDB writes it, e.g. #11:1.
+func (rid RID) String() string {
+  return "#" + strconv.Itoa(rid.ClusterID) + ":" + strconv.FormatInt(rid.Position, 10)
+}
+
+// IsTemporary reports whether the record id was assigned to a record that has
+// not been saved yet.
+func (rid RID) IsTemporary() bool {
+  return rid.ClusterID < 0 || rid.Position < 0
+}
+
+// IsPersistent reports whether the record id points to a saved record.
+func (rid RID) IsPersistent() bool {
+  return !rid.IsTemporary()
+}
+
+// MarshalJSON writes the record id as a JSON string.
+func (rid RID) MarshalJSON() ([]byte, error) {
+  return json.Marshal(rid.String())
+}
+
+// UnmarshalJSON reads a record id from a JSON string. Links expanded by a
+// fetch plan arrive as whole records, in which case their @rid is used.
+func (rid *RID) UnmarshalJSON(data []byte) error {
+  var s string
+
+  switch {
+  case string(data) == "null":
+    return nil
+  case len(data) > 0 && data[0] == '{':
+    var record struct {
+      RID string `json:"@rid"`
+    }
+    if err := json.Unmarshal(data, &record); err != nil {
+      return err
+    }
+    s = record.RID
+  default:
+    if err := json.Unmarshal(data, &s); err != nil {
+      return err
+    }
+  }
+
+  parsed, err := ParseRID(s)
+  if err != nil {
+    return err
+  }
+
+  *rid = parsed
+
+  return nil
+}
@@ -0,0 +1,56 @@
+package goog
+
+import (
+  "encoding/json"
+  "errors"
+  "testing"
+)
+
+func TestParseRID(t *testing.T) {
+  rid, err := ParseRID("#11:1")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if rid.ClusterID != 11 || rid.Position != 1 || rid.String() != "#11:1" || !rid.IsPersistent() {
+    t.Fatalf("Unexpected record id %#v.", rid)
+  }
+
+  if rid, err = ParseRID("-2:1"); err != nil || !rid.IsTemporary() || rid.String() != "#-2:1" {
+    t.Fatalf("Unexpected temporary record id %#v (%v).", rid, err)
+  }
+
+  for _, s := range []string{"", "#11", "#a:1", "#11:b", "#1:2:3"} {
+    if _, err = ParseRID(s); !errors.Is(err, ErrInvalidRID) {
+      t.Fatalf("Expecting ErrInvalidRID for %q, got %v.", s, err)
+    }
+  }
+}
+
+func TestRIDJSON(t *testing.T) {
+  type person struct {
+    RID  RID   `json:"@rid"`
+    Out  []RID `json:"out"`
+    Name string
+  }
+
+  var p person
+
+  err := json.Unmarshal([]byte(`{"@rid": "#-2:1", "out": ["#11:1", {"@rid": "#11:2", "name": "Beto"}], "Name": "Nor"}`), &p)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if p.RID != (RID{-2, 1}) || len(p.Out) != 2 || p.Out[1] != (RID{11, 2}) {
+    t.Fatalf("Unexpected decoded value %#v.", p)
+  }
+
+  buf, err := json.Marshal(p)
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if string(buf) != `{"@rid":"#-2:1","out":["#11:1","#11:2"],"Name":"Nor"}` {
+    t.Fatalf("Unexpected encoded value %s.", buf)
+  }
+}
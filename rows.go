@@ -0,0 +1,275 @@
+package goog
+
+import (
+  "context"
+  "encoding/json"
+  "fmt"
+  "io"
+  "strconv"
+
+  l4g "code.google.com/p/log4go"
+)
+
+// Rows iterates over the records of a query, decoding them one at a time as
+// they are read from the server instead of loading them all in memory:
+//
+//	rows, err := db.QueryRows("select from Person", 0, "")
+//	if err != nil {
+//		return err
+//	}
+//	defer rows.Close()
+//
+//	for rows.Next() {
+//		var p Person
+//		if err := rows.Scan(&p); err != nil {
+//			return err
+//		}
+//	}
+//	return rows.Err()
+type Rows struct {
+  ctx       context.Context
+  db        *DataBase
+  path      func(last RID) string
+  pageSize  int
+  last      RID
+  count     int
+  body      io.ReadCloser
+  dec       *json.Decoder
+  doc       *Document
+  err       error
+  exhausted bool
+}
+
+// QueryRows runs a query like Query does, returning an iterator over the
+// records found.
+//
+// The records are read as the response arrives, so Config.Timeout, which
+// bounds each request up to the end of its response, also bounds the whole
+// iteration: a result set streaming for longer is cut off with an error. Use
+// Paginate for large exports, each page being a request of its own, or a
+// connection without Timeout along with QueryRowsContext.
+func (db *DataBase) QueryRows(sql string, limit int, fetchPlan FetchPlan) (*Rows, error) {
+  l4g.Trace("Inside QueryRows")
+
+  return db.QueryRowsContext(context.Background(), sql, limit, fetchPlan)
+}
+
+// QueryRowsContext is like QueryRows, reading records until ctx is done.
+func (db *DataBase) QueryRowsContext(ctx context.Context, sql string, limit int, fetchPlan FetchPlan) (*Rows, error) {
+  l4g.Trace("Inside QueryRowsContext")
+
+  if fetchPlan == "" {
+    fetchPlan = db.fetchPlan
+  }
+
+  path := queryPath(db.name, sql, limit, fetchPlan)
+  rows := &Rows{ctx: ctx, db: db, path: func(RID) string { return path }}
+
+  if err := rows.open(); err != nil {
+    return nil, err
+  }
+
+  return rows, nil
+}
+
+// Paginate iterates over every record of a class matching the condition in
+// where, which may be left empty, asking the server for pageSize records at
+// a time: each page starts after the id of the last record of the previous
+// one (where @rid > last limit pageSize), so records aren't skipped or read
+// twice as other ones are created or deleted. Config.Timeout applies to each
+// page rather than to the whole iteration.
+func (db *DataBase) Paginate(class, where string, pageSize int, fetchPlan FetchPlan) (*Rows, error) {
+  l4g.Trace("Inside Paginate")
+
+  return db.PaginateContext(context.Background(), class, where, pageSize, fetchPlan)
+}
+
+// PaginateContext is like Paginate, reading records until ctx is done.
+func (db *DataBase) PaginateContext(ctx context.Context, class, where string, pageSize int, fetchPlan FetchPlan) (*Rows, error) {
+  l4g.Trace("Inside PaginateContext")
+
+  if err := checkIdentifiers(class); err != nil || class == "" {
+    return nil, fmt.Errorf("%w %q.", ErrInvalidIdentifier, class)
+  }
+
+  if pageSize < 1 {
+    pageSize = DEFAULT_PAGE_SIZE
+  }
+
+  if fetchPlan == "" {
+    fetchPlan = db.fetchPlan
+  }
+
+  rows := &Rows{
+    ctx:      ctx,
+    db:       db,
+    pageSize: pageSize,
+    last:     RID{ClusterID: -1, Position: -1},
+    path: func(last RID) string {
+      return queryPath(db.name, pageQuery(class, where, last, pageSize), pageSize, fetchPlan)
+    },
+  }
+
+  if err := rows.open(); err != nil {
+    return nil, err
+  }
+
+  return rows, nil
+}
+
+// DEFAULT_PAGE_SIZE is the number of records Paginate asks for at a time when
+// given no page size.
+const DEFAULT_PAGE_SIZE = 1000
+
+// pageQuery selects the page of records of class following last.
+func pageQuery(class, where string, last RID, pageSize int) string {
+  sql := "select from " + class + " where @rid > " + last.String()
+  if where != "" {
+    sql += " and (" + where + ")"
+  }
+
+  return sql + " limit " + strconv.Itoa(pageSize)
+}
+
+// Next reads the next record, returning false when there are no more or an
+// error happened, which Err then returns. The last page is released then.
+func (r *Rows) Next() bool {
+  r.doc = nil
+
+  for r.err == nil && !r.exhausted {
+    if r.dec != nil && r.dec.More() {
+      var doc Document
+      if r.err = r.dec.Decode(&doc); r.err != nil {
+        break
+      }
+
+      resolveLinks(&doc)
+
+      r.doc = &doc
+      r.last = doc.RID
+      r.count++
+
+      return true
+    }
+
+    r.closeBody()
+
+    // A full page means there may be more to read.
+    if r.pageSize == 0 || r.count < r.pageSize {
+      r.exhausted = true
+      break
+    }
+
+    r.err = r.open()
+  }
+
+  r.closeBody()
+
+  return false
+}
+
+// Document returns the record Next read.
+func (r *Rows) Document() *Document {
+  return r.doc
+}
+
+// Scan copies the record Next read into dst, a pointer to a struct following
+// the rules described on QueryInto, or to a Document.
+func (r *Rows) Scan(dst interface{}) error {
+  if r.doc == nil {
+    return ErrNoResult
+  }
+
+  if doc, ok := dst.(*Document); ok {
+    *doc = *r.doc
+    return nil
+  }
+
+  return r.doc.Decode(dst)
+}
+
+// Err returns the error that stopped Next, if any.
+func (r *Rows) Err() error {
+  return r.err
+}
+
+// Close stops the iteration, releasing the connection with the server. It is
+// safe to call more than once.
+func (r *Rows) Close() error {
+  r.exhausted = true
+  r.doc = nil
+
+  return r.closeBody()
+}
+
+// open sends the request for the next page and moves to the first record of
+// its "result" array, skipping any other member of the response.
+func (r *Rows) open() error {
+  var body io.ReadCloser
+
+  if err := r.db.get(r.ctx, &body, r.path(r.last), nil); err != nil {
+    return err
+  }
+
+  r.body = body
+  r.dec = json.NewDecoder(body)
+  r.count = 0
+
+  if err := expectDelim(r.dec, '{'); err != nil {
+    r.closeBody()
+    return err
+  }
+
+  for r.dec.More() {
+    token, err := r.dec.Token()
+    if err != nil {
+      r.closeBody()
+      return err
+    }
+
+    if token == "result" {
+      if err = expectDelim(r.dec, '['); err != nil {
+        r.closeBody()
+      }
+      return err
+    }
+
+    var skipped json.RawMessage
+    if err = r.dec.Decode(&skipped); err != nil {
+      r.closeBody()
+      return err
+    }
+  }
+
+  // No result at all.
+  r.closeBody()
+  r.exhausted = true
+
+  return nil
+}
+
+func (r *Rows) closeBody() error {
+  var err error
+
+  if r.body != nil {
+    err = r.body.Close()
+    r.body = nil
+  }
+
+  r.dec = nil
+
+  return err
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+  token, err := dec.Token()
+  if err != nil {
+    return err
+  }
+
+  if token != delim {
+    return fmt.Errorf("%w: expecting %s, got %v.", ErrMalformedResult, delim, token)
+  }
+
+  return nil
+}
@@ -0,0 +1,138 @@
+package goog
+
+import (
+  "errors"
+  "fmt"
+  "net/http"
+  "net/url"
+  "regexp"
+  "strconv"
+  "strings"
+  "testing"
+)
+
+func TestQueryRows(t *testing.T) {
+  ts, db := connectTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    w.Write([]byte(`{"notification": {"skipped": [1, 2]}, "result": [`))
+    for i := 0; i < 1000; i++ {
+      if i > 0 {
+        w.Write([]byte(","))
+      }
+      fmt.Fprintf(w, `{"@type": "d", "@rid": "#11:%d", "@version": 1, "@class": "Person", "name": "Person %d"}`, i, i)
+    }
+    w.Write([]byte(`], "elapsed": 12}`))
+  })
+  defer ts.Close()
+
+  rows, err := db.QueryRows("select from Person", 0, "")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer rows.Close()
+
+  type person struct {
+    RID  RID `goog:"@rid"`
+    Name string
+  }
+
+  var count int
+  for rows.Next() {
+    var p person
+    if err = rows.Scan(&p); err != nil {
+      t.Fatal(err)
+    }
+    if p.RID.Position != int64(count) || p.Name != "Person "+strconv.Itoa(count) {
+      t.Fatalf("Unexpected record %+v.", p)
+    }
+    count++
+  }
+
+  if rows.Err() != nil || count != 1000 {
+    t.Fatalf("Unexpected end after %d records: %v.", count, rows.Err())
+  }
+
+  if rows.Next() || rows.Close() != nil {
+    t.Fatalf("Expecting a closed iterator.")
+  }
+}
+
+func TestQueryRowsErrors(t *testing.T) {
+  ts, db := connectTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    if strings.Contains(r.URL.Path, "Missing") {
+      w.WriteHeader(http.StatusNotFound)
+      return
+    }
+    w.Write([]byte(`{"result": [{"@type": "d", "@rid": "#11:0", "@version": 1}, {"@type": `))
+  })
+  defer ts.Close()
+
+  if _, err := db.QueryRows("select from Missing", 0, ""); !errors.Is(err, ErrNotFound) {
+    t.Fatalf("Expecting ErrNotFound, got %v.", err)
+  }
+
+  rows, err := db.QueryRows("select from Person", 0, "")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if !rows.Next() || rows.Next() || rows.Err() == nil {
+    t.Fatalf("Expecting an error on the truncated record.")
+  }
+}
+
+func TestPaginate(t *testing.T) {
+  page := regexp.MustCompile(`^select from Person where @rid > #(-?\d+):(-?\d+) and \(born > 1900\) limit 3$`)
+  var requests int
+
+  ts, db := connectTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+    requests++
+    sql, _ := url.PathUnescape(strings.Split(r.URL.Path, "/")[4])
+
+    match := page.FindStringSubmatch(sql)
+    if match == nil {
+      t.Errorf("Unexpected query %q.", sql)
+      w.WriteHeader(http.StatusBadRequest)
+      return
+    }
+
+    // Seven records, #11:0 to #11:6.
+    last, _ := strconv.Atoi(match[2])
+    if match[1] == "-1" {
+      last = -1
+    }
+
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    w.Write([]byte(`{"result": [`))
+    for i := last + 1; i < 7 && i <= last+3; i++ {
+      if i > last+1 {
+        w.Write([]byte(","))
+      }
+      fmt.Fprintf(w, `{"@type": "d", "@rid": "#11:%d", "@version": 1, "@class": "Person"}`, i)
+    }
+    w.Write([]byte(`]}`))
+  })
+  defer ts.Close()
+
+  rows, err := db.Paginate("Person", "born > 1900", 3, "")
+  if err != nil {
+    t.Fatal(err)
+  }
+  defer rows.Close()
+
+  var seen []string
+  for rows.Next() {
+    var doc Document
+    rows.Scan(&doc)
+    seen = append(seen, doc.RID.String())
+  }
+
+  if rows.Err() != nil || len(seen) != 7 || seen[6] != "#11:6" || requests != 3 {
+    t.Fatalf("Unexpected records %v after %d requests (%v).", seen, requests, rows.Err())
+  }
+
+  if _, err = db.Paginate("Person; drop", "", 3, ""); !errors.Is(err, ErrInvalidIdentifier) {
+    t.Fatalf("Expecting ErrInvalidIdentifier, got %v.", err)
+  }
+}
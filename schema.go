@@ -0,0 +1,348 @@
+package goog
+
+import (
+  "context"
+  "fmt"
+  "strconv"
+  "strings"
+
+  l4g "code.google.com/p/log4go"
+)
+
+// PropertyType is the type of a schema property.
+type PropertyType string
+
+const (
+  BOOLEAN      PropertyType = "BOOLEAN"
+  SHORT        PropertyType = "SHORT"
+  INTEGER      PropertyType = "INTEGER"
+  LONG         PropertyType = "LONG"
+  FLOAT        PropertyType = "FLOAT"
+  DOUBLE       PropertyType = "DOUBLE"
+  DECIMAL      PropertyType = "DECIMAL"
+  BYTE         PropertyType = "BYTE"
+  STRING       PropertyType = "STRING"
+  BINARY       PropertyType = "BINARY"
+  DATE         PropertyType = "DATE"
+  DATETIME     PropertyType = "DATETIME"
+  EMBEDDED     PropertyType = "EMBEDDED"
+  EMBEDDEDLIST PropertyType = "EMBEDDEDLIST"
+  EMBEDDEDSET  PropertyType = "EMBEDDEDSET"
+  EMBEDDEDMAP  PropertyType = "EMBEDDEDMAP"
+  LINK         PropertyType = "LINK"
+  LINKLIST     PropertyType = "LINKLIST"
+  LINKSET      PropertyType = "LINKSET"
+  LINKMAP      PropertyType = "LINKMAP"
+  LINKBAG      PropertyType = "LINKBAG"
+  ANY          PropertyType = "ANY"
+)
+
+// PropertyAttribute is a constraint that can be set on a schema property.
+type PropertyAttribute string
+
+const (
+  MANDATORY PropertyAttribute = "MANDATORY"
+  NOTNULL   PropertyAttribute = "NOTNULL"
+  READONLY  PropertyAttribute = "READONLY"
+  MIN       PropertyAttribute = "MIN"
+  MAX       PropertyAttribute = "MAX"
+  REGEXP    PropertyAttribute = "REGEXP"
+)
+
+// IndexType is the kind of an index.
+type IndexType string
+
+const (
+  UNIQUE     IndexType = "UNIQUE"
+  NOTUNIQUE  IndexType = "NOTUNIQUE"
+  FULLTEXT   IndexType = "FULLTEXT"
+  DICTIONARY IndexType = "DICTIONARY"
+)
+
+// ClassInfo describes a class as returned by the class endpoint.
+type ClassInfo struct {
+  Name           string         `json:"name"`
+  SuperClass     string         `json:"superClass"`
+  SuperClasses   []string       `json:"superClasses"`
+  Abstract       bool           `json:"abstract"`
+  StrictMode     bool           `json:"strictmode"`
+  Clusters       []int          `json:"clusters"`
+  DefaultCluster int            `json:"defaultCluster"`
+  Records        int64          `json:"records"`
+  Properties     []PropertyInfo `json:"properties"`
+  Indexes        []IndexInfo    `json:"indexes"`
+}
+
+// PropertyInfo describes a property of a class. Min, Max and Regexp are empty
+// when not set.
+type PropertyInfo struct {
+  Name        string       `json:"name"`
+  Type        PropertyType `json:"type"`
+  LinkedClass string       `json:"linkedClass"`
+  LinkedType  PropertyType `json:"linkedType"`
+  Mandatory   bool         `json:"mandatory"`
+  ReadOnly    bool         `json:"readonly"`
+  NotNull     bool         `json:"notNull"`
+  Min         string       `json:"min"`
+  Max         string       `json:"max"`
+  Regexp      string       `json:"regexp"`
+}
+
+// IndexInfo describes an index defined on a class.
+type IndexInfo struct {
+  Name   string    `json:"name"`
+  Type   IndexType `json:"type"`
+  Fields []string  `json:"fields"`
+}
+
+// Schema manages the classes, properties and indexes of a database.
+type Schema struct {
+  db *DataBase
+}
+
+// Schema returns the schema manager of the database.
+func (db *DataBase) Schema() *Schema {
+  return &Schema{db: db}
+}
+
+// CreateClass creates a class, extending superclass unless it is empty.
+// Vertex classes extend V and edge classes extend E.
+func (s *Schema) CreateClass(name, superclass string, abstract bool) error {
+  l4g.Trace("Inside CreateClass")
+
+  return s.CreateClassContext(context.Background(), name, superclass, abstract)
+}
+
+// CreateClassContext is like CreateClass, the request being canceled when ctx
+// is done.
+func (s *Schema) CreateClassContext(ctx context.Context, name, superclass string, abstract bool) error {
+  l4g.Trace("Inside CreateClassContext")
+
+  if err := requireIdentifiers(name); err != nil {
+    return err
+  }
+
+  if err := checkIdentifiers(superclass); err != nil {
+    return err
+  }
+
+  sql := "create class " + name
+  if superclass != "" {
+    sql += " extends " + superclass
+  }
+  if abstract {
+    sql += " abstract"
+  }
+
+  _, err := s.db.CommandContext(ctx, SQL, sql)
+  return err
+}
+
+// DropClass removes a class. It fails if the class still has records.
+func (s *Schema) DropClass(name string) error {
+  l4g.Trace("Inside DropClass")
+
+  return s.DropClassContext(context.Background(), name)
+}
+
+// DropClassContext is like DropClass, the request being canceled when ctx is
+// done.
+func (s *Schema) DropClassContext(ctx context.Context, name string) error {
+  l4g.Trace("Inside DropClassContext")
+
+  if err := requireIdentifiers(name); err != nil {
+    return err
+  }
+
+  _, err := s.db.CommandContext(ctx, SQL, "drop class "+name)
+  return err
+}
+
+// CreateProperty adds a property to a class. linkedClass is the class of the
+// records a LINK or LINKLIST property points to, or of the documents an
+// EMBEDDED property holds; it may be left empty.
+func (s *Schema) CreateProperty(class, name string, propType PropertyType, linkedClass string) error {
+  l4g.Trace("Inside CreateProperty")
+
+  return s.CreatePropertyContext(context.Background(), class, name, propType, linkedClass)
+}
+
+// CreatePropertyContext is like CreateProperty, the request being canceled when
+// ctx is done.
+func (s *Schema) CreatePropertyContext(ctx context.Context, class, name string, propType PropertyType, linkedClass string) error {
+  l4g.Trace("Inside CreatePropertyContext")
+
+  if err := requireIdentifiers(class, name, string(propType)); err != nil {
+    return err
+  }
+
+  if err := checkIdentifiers(linkedClass); err != nil {
+    return err
+  }
+
+  sql := "create property " + class + "." + name + " " + string(propType)
+  if linkedClass != "" {
+    sql += " " + linkedClass
+  }
+
+  _, err := s.db.CommandContext(ctx, SQL, sql)
+  return err
+}
+
+// DropProperty removes a property from a class.
+func (s *Schema) DropProperty(class, name string) error {
+  l4g.Trace("Inside DropProperty")
+
+  return s.DropPropertyContext(context.Background(), class, name)
+}
+
+// DropPropertyContext is like DropProperty, the request being canceled when ctx
+// is done.
+func (s *Schema) DropPropertyContext(ctx context.Context, class, name string) error {
+  l4g.Trace("Inside DropPropertyContext")
+
+  if err := requireIdentifiers(class, name); err != nil {
+    return err
+  }
+
+  _, err := s.db.CommandContext(ctx, SQL, "drop property "+class+"."+name)
+  return err
+}
+
+// AlterProperty sets a constraint on a property: a bool for MANDATORY,
+// NOTNULL and READONLY, a number or string for MIN and MAX, a string for
+// REGEXP. A nil value removes the constraint.
+func (s *Schema) AlterProperty(class, name string, attribute PropertyAttribute, value interface{}) error {
+  l4g.Trace("Inside AlterProperty")
+
+  return s.AlterPropertyContext(context.Background(), class, name, attribute, value)
+}
+
+// AlterPropertyContext is like AlterProperty, the request being canceled when
+// ctx is done.
+func (s *Schema) AlterPropertyContext(ctx context.Context, class, name string, attribute PropertyAttribute, value interface{}) error {
+  l4g.Trace("Inside AlterPropertyContext")
+
+  if err := requireIdentifiers(class, name, string(attribute)); err != nil {
+    return err
+  }
+
+  var literal string
+
+  switch v := value.(type) {
+  case nil:
+    literal = "null"
+  case bool:
+    literal = strconv.FormatBool(v)
+  case int, int8, int16, int32, int64, float32, float64:
+    literal = fmt.Sprint(v)
+  case string:
+    literal = strconv.Quote(v)
+  default:
+    return fmt.Errorf("Unsupported value %T for %s.", value, attribute)
+  }
+
+  _, err := s.db.CommandContext(ctx, SQL, "alter property "+class+"."+name+" "+string(attribute)+" "+literal)
+  return err
+}
+
+// CreateIndex indexes fields of a class. The index is named after the class
+// and its fields (Person.name) when name is empty.
+func (s *Schema) CreateIndex(name, class string, fields []string, indexType IndexType) error {
+  l4g.Trace("Inside CreateIndex")
+
+  return s.CreateIndexContext(context.Background(), name, class, fields, indexType)
+}
+
+// CreateIndexContext is like CreateIndex, the request being canceled when ctx
+// is done.
+func (s *Schema) CreateIndexContext(ctx context.Context, name, class string, fields []string, indexType IndexType) error {
+  l4g.Trace("Inside CreateIndexContext")
+
+  if len(fields) == 0 {
+    return fmt.Errorf("%w %q.", ErrInvalidIdentifier, "")
+  }
+
+  if err := requireIdentifiers(append([]string{class, string(indexType)}, fields...)...); err != nil {
+    return err
+  }
+
+  if name == "" {
+    name = class + "." + strings.Join(fields, "_")
+  } else if err := checkIdentifiers(name); err != nil {
+    return err
+  }
+
+  _, err := s.db.CommandContext(ctx, SQL, "create index "+name+" on "+class+" ("+strings.Join(fields, ", ")+") "+string(indexType))
+  return err
+}
+
+// DropIndex removes an index.
+func (s *Schema) DropIndex(name string) error {
+  l4g.Trace("Inside DropIndex")
+
+  return s.DropIndexContext(context.Background(), name)
+}
+
+// DropIndexContext is like DropIndex, the request being canceled when ctx is
+// done.
+func (s *Schema) DropIndexContext(ctx context.Context, name string) error {
+  l4g.Trace("Inside DropIndexContext")
+
+  for _, part := range strings.Split(name, ".") {
+    if err := requireIdentifiers(part); err != nil {
+      return err
+    }
+  }
+
+  _, err := s.db.CommandContext(ctx, SQL, "drop index "+name)
+  return err
+}
+
+// Class describes a class, its properties and its indexes. It fails with
+// ErrNotFound when there is no such class.
+func (s *Schema) Class(name string) (*ClassInfo, error) {
+  l4g.Trace("Inside Class")
+
+  return s.ClassContext(context.Background(), name)
+}
+
+// ClassContext is like Class, the request being canceled when ctx is done.
+func (s *Schema) ClassContext(ctx context.Context, name string) (*ClassInfo, error) {
+  l4g.Trace("Inside ClassContext")
+  var info ClassInfo
+
+  if err := requireIdentifiers(name); err != nil {
+    return nil, err
+  }
+
+  if err := s.db.get(ctx, &info, CLASS_URL+s.db.name+"/"+name, nil); err != nil {
+    return nil, err
+  }
+
+  return &info, nil
+}
+
+// checkIdentifiers makes sure names can be written unquoted in a statement.
+// Empty names are skipped, being optional.
+func checkIdentifiers(names ...string) error {
+  for _, name := range names {
+    if name != "" && !identifier.MatchString(name) {
+      return fmt.Errorf("%w %q.", ErrInvalidIdentifier, name)
+    }
+  }
+
+  return nil
+}
+
+// requireIdentifiers is like checkIdentifiers for names a statement can't do
+// without, empty names being rejected too.
+func requireIdentifiers(names ...string) error {
+  for _, name := range names {
+    if name == "" {
+      return fmt.Errorf("%w %q.", ErrInvalidIdentifier, name)
+    }
+  }
+
+  return checkIdentifiers(names...)
+}
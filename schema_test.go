@@ -0,0 +1,114 @@
+package goog
+
+import (
+  "errors"
+  "io/ioutil"
+  "net/http"
+  "testing"
+)
+
+func TestSchema(t *testing.T) {
+  var statements []string
+
+  ts, db := connectTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    if r.Method == "GET" {
+      if r.URL.Path != "/class/geneology/Person" {
+        w.WriteHeader(http.StatusNotFound)
+        return
+      }
+      w.Write([]byte(`{"name": "Person", "superClass": "V", "superClasses": ["V"], "alias": null, "abstract": false,
+        "strictmode": false, "clusters": [11], "defaultCluster": 11, "records": 3,
+        "properties": [{"name": "name", "type": "STRING", "mandatory": true, "readonly": false, "notNull": true,
+          "min": "1", "max": null, "regexp": null, "collate": "default"}],
+        "indexes": [{"name": "Person.name", "type": "UNIQUE", "fields": ["name"]}]}`))
+      return
+    }
+    buf, _ := ioutil.ReadAll(r.Body)
+    statements = append(statements, string(buf))
+    w.Write([]byte(`{"result": [{"@type": "d", "@version": 0, "value": 11}]}`))
+  })
+  defer ts.Close()
+
+  schema := db.Schema()
+
+  steps := []error{
+    schema.CreateClass("Person", "V", false),
+    schema.CreateClass("Referrer", "E", false),
+    schema.CreateClass("Living", "", true),
+    schema.CreateProperty("Person", "name", STRING, ""),
+    schema.CreateProperty("Person", "best", LINK, "Person"),
+    schema.AlterProperty("Person", "name", MANDATORY, true),
+    schema.AlterProperty("Person", "name", MIN, 1),
+    schema.AlterProperty("Person", "email", REGEXP, `[^@]+@[^"]+`),
+    schema.CreateIndex("", "Person", []string{"name"}, UNIQUE),
+    schema.CreateIndex("byNameAndAge", "Person", []string{"name", "age"}, NOTUNIQUE),
+    schema.DropIndex("Person.name"),
+    schema.DropClass("Living"),
+  }
+
+  for _, err := range steps {
+    if err != nil {
+      t.Fatal(err)
+    }
+  }
+
+  expected := []string{
+    `create class Person extends V`,
+    `create class Referrer extends E`,
+    `create class Living abstract`,
+    `create property Person.name STRING`,
+    `create property Person.best LINK Person`,
+    `alter property Person.name MANDATORY true`,
+    `alter property Person.name MIN 1`,
+    `alter property Person.email REGEXP "[^@]+@[^\"]+"`,
+    `create index Person.name on Person (name) UNIQUE`,
+    `create index byNameAndAge on Person (name, age) NOTUNIQUE`,
+    `drop index Person.name`,
+    `drop class Living`,
+  }
+
+  for i := range expected {
+    if statements[i] != expected[i] {
+      t.Fatalf("Expecting %q, got %q.", expected[i], statements[i])
+    }
+  }
+
+  if err := schema.CreateClass("Person extends V; drop class X", "", false); !errors.Is(err, ErrInvalidIdentifier) {
+    t.Fatalf("Expecting ErrInvalidIdentifier, got %v.", err)
+  }
+
+  invalid := []error{
+    schema.CreateClass("", "V", false),
+    schema.CreateIndex("", "", []string{"name"}, UNIQUE),
+    schema.CreateIndex("", "Person", nil, UNIQUE),
+    schema.CreateIndex("", "Person", []string{"name", ""}, UNIQUE),
+  }
+
+  for i, err := range invalid {
+    if !errors.Is(err, ErrInvalidIdentifier) {
+      t.Fatalf("Expecting ErrInvalidIdentifier for statement %d, got %v.", i, err)
+    }
+  }
+
+  if len(statements) != len(expected) {
+    t.Fatalf("Invalid statements should not be sent: %q.", statements[len(expected):])
+  }
+
+  info, err := schema.Class("Person")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if info.SuperClass != "V" || info.Records != 3 || len(info.Properties) != 1 || len(info.Indexes) != 1 {
+    t.Fatalf("Unexpected class %#v.", info)
+  }
+
+  if p := info.Properties[0]; p.Type != STRING || !p.Mandatory || !p.NotNull || p.Min != "1" || p.Max != "" {
+    t.Fatalf("Unexpected property %#v.", p)
+  }
+
+  if _, err = schema.Class("Animal"); !errors.Is(err, ErrNotFound) {
+    t.Fatalf("Expecting ErrNotFound, got %v.", err)
+  }
+}
@@ -0,0 +1,144 @@
+package goog
+
+import (
+  "net/url"
+
+  l4g "code.google.com/p/log4go"
+  "github.com/hiphoox/goog/rest"
+)
+
+// StorageType is where a database keeps its records.
+type StorageType string
+
+const (
+  PLOCAL StorageType = "plocal"
+  MEMORY StorageType = "memory"
+)
+
+// DatabaseType is the model of a database.
+type DatabaseType string
+
+const (
+  GRAPH    DatabaseType = "graph"
+  DOCUMENT DatabaseType = "document"
+)
+
+// Server administers an OrientDB server, usually with its root credentials.
+type Server struct {
+  address string
+  client  *rest.Client
+}
+
+// ServerInfo describes a running server as returned by the server endpoint.
+type ServerInfo struct {
+  Connections []ConnectionInfo `json:"connections"`
+  Storages    []StorageInfo    `json:"storages"`
+  Properties  []PropertyValue  `json:"properties"`
+}
+
+// ConnectionInfo describes a client connected to the server.
+type ConnectionInfo struct {
+  ID            string `json:"connectionId"`
+  RemoteAddress string `json:"remoteAddress"`
+  Database      string `json:"db"`
+  User          string `json:"user"`
+  Protocol      string `json:"protocol"`
+}
+
+// StorageInfo describes a storage opened by the server.
+type StorageInfo struct {
+  Name        string `json:"name"`
+  Type        string `json:"type"`
+  Path        string `json:"path"`
+  ActiveUsers int    `json:"activeUsers"`
+}
+
+// PropertyValue is a configuration property of the server.
+type PropertyValue struct {
+  Name  string `json:"name"`
+  Value string `json:"value"`
+}
+
+// NewServer creates an administration client for the given server (host:port).
+// Credentials are checked on the first request.
+func NewServer(server, login, password string) (*Server, error) {
+  l4g.Trace("Inside NewServer")
+
+  return NewServerWithConfig(Config{Server: server, Login: login, Password: password})
+}
+
+// NewServerWithConfig creates an administration client for the server
+// described by config, whose Database is ignored. With several servers, the
+// first one is administered.
+func NewServerWithConfig(config Config) (*Server, error) {
+  l4g.Trace("Inside NewServerWithConfig")
+
+  servers := config.servers()
+  if len(servers) == 0 {
+    return nil, ErrNoServer
+  }
+
+  client, err := config.newClient(servers[0])
+  if err != nil {
+    return nil, err
+  }
+
+  client.SetBasicAuth(config.Login, config.Password)
+
+  return &Server{address: servers[0], client: client}, nil
+}
+
+// ListDatabases returns the names of the databases on the server.
+func (s *Server) ListDatabases() ([]string, error) {
+  l4g.Trace("Inside ListDatabases")
+  var res struct {
+    Databases []string `json:"databases"`
+  }
+
+  err := serverError(s.client.Get(&res, LIST_DATABASES_URL, nil))
+
+  return res.Databases, err
+}
+
+// Exists tells whether the server has a database with the given name.
+func (s *Server) Exists(name string) (bool, error) {
+  names, err := s.ListDatabases()
+  if err != nil {
+    return false, err
+  }
+
+  for _, n := range names {
+    if n == name {
+      return true, nil
+    }
+  }
+
+  return false, nil
+}
+
+// CreateDatabase creates a database with the given storage and model.
+func (s *Server) CreateDatabase(name string, storage StorageType, dbType DatabaseType) error {
+  l4g.Trace("Inside CreateDatabase")
+
+  return serverError(s.client.PostRaw(nil, DATABASE_URL+url.PathEscape(name)+"/"+string(storage)+"/"+string(dbType), nil))
+}
+
+// DropDatabase removes a database and all of its records.
+func (s *Server) DropDatabase(name string) error {
+  l4g.Trace("Inside DropDatabase")
+
+  return databaseError(serverError(s.client.Delete(nil, DATABASE_URL+url.PathEscape(name), nil)))
+}
+
+// ServerInfo returns the connections, storages and configuration of the
+// server.
+func (s *Server) ServerInfo() (*ServerInfo, error) {
+  l4g.Trace("Inside ServerInfo")
+  var info ServerInfo
+
+  if err := serverError(s.client.Get(&info, SERVER_URL, nil)); err != nil {
+    return nil, err
+  }
+
+  return &info, nil
+}
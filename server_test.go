@@ -0,0 +1,101 @@
+package goog
+
+import (
+  "errors"
+  "net/http"
+  "net/http/httptest"
+  "net/url"
+  "strings"
+  "testing"
+)
+
+func TestServer(t *testing.T) {
+  databases := map[string]string{"geneology": "plocal/graph"}
+
+  ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    if user, pass, _ := r.BasicAuth(); user != "root" || pass != "secret" {
+      w.WriteHeader(http.StatusUnauthorized)
+      return
+    }
+
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    parts := strings.SplitN(strings.TrimPrefix(r.URL.EscapedPath(), DATABASE_URL), "/", 2)
+    name, _ := url.PathUnescape(parts[0])
+
+    switch {
+    case r.URL.Path == LIST_DATABASES_URL:
+      var names []string
+      for name := range databases {
+        names = append(names, `"`+name+`"`)
+      }
+      w.Write([]byte(`{"@type": "d", "@version": 0, "databases": [` + strings.Join(names, ", ") + `]}`))
+    case r.URL.Path == SERVER_URL:
+      w.Write([]byte(`{"connections": [{"connectionId": "7", "remoteAddress": "127.0.0.1:5123", "db": "geneology", "user": "root", "protocol": "http"}],
+        "storages": [{"name": "geneology", "type": "OLocalPaginatedStorage", "path": "databases/geneology", "activeUsers": 1}],
+        "properties": [{"name": "db.pool.max", "value": "100"}]}`))
+    case r.Method == "POST":
+      databases[name] = parts[1]
+      w.Write([]byte(`{"classes": []}`))
+    case r.Method == "DELETE":
+      if _, ok := databases[name]; !ok {
+        w.WriteHeader(http.StatusNotFound)
+        return
+      }
+      delete(databases, name)
+      w.WriteHeader(http.StatusNoContent)
+    }
+  }))
+  defer ts.Close()
+
+  s, err := NewServer(strings.TrimPrefix(ts.URL, HTTP_PREFIX), "root", "secret")
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if err = s.CreateDatabase("scratch", MEMORY, DOCUMENT); err != nil {
+    t.Fatal(err)
+  }
+
+  if databases["scratch"] != "memory/document" {
+    t.Fatalf("Unexpected databases %v.", databases)
+  }
+
+  // Names are escaped rather than read as part of the path.
+  if err = s.CreateDatabase("old/new db?", MEMORY, DOCUMENT); err != nil || databases["old/new db?"] != "memory/document" {
+    t.Fatalf("Unexpected databases %v (%v).", databases, err)
+  }
+
+  if err = s.DropDatabase("old/new db?"); err != nil {
+    t.Fatal(err)
+  }
+
+  if exists, err := s.Exists("scratch"); err != nil || !exists {
+    t.Fatalf("Expecting scratch to exist (%v).", err)
+  }
+
+  if err = s.DropDatabase("scratch"); err != nil {
+    t.Fatal(err)
+  }
+
+  if exists, err := s.Exists("scratch"); err != nil || exists {
+    t.Fatalf("Expecting scratch to be gone (%v).", err)
+  }
+
+  if err = s.DropDatabase("scratch"); !errors.Is(err, ErrDatabaseNotFound) {
+    t.Fatalf("Expecting ErrDatabaseNotFound, got %v.", err)
+  }
+
+  info, err := s.ServerInfo()
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if len(info.Connections) != 1 || info.Connections[0].Database != "geneology" || info.Storages[0].ActiveUsers != 1 || info.Properties[0].Value != "100" {
+    t.Fatalf("Unexpected server info %#v.", info)
+  }
+
+  s, _ = NewServer(strings.TrimPrefix(ts.URL, HTTP_PREFIX), "root", "wrong")
+  if _, err = s.ListDatabases(); !errors.Is(err, ErrUnauthorized) {
+    t.Fatalf("Expecting ErrUnauthorized, got %v.", err)
+  }
+}
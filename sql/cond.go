@@ -0,0 +1,161 @@
+package sql
+
+// Cond is a condition of a WHERE clause.
+type Cond interface {
+  build(b *builder)
+}
+
+type comparison struct {
+  field    string
+  operator string
+  value    interface{}
+}
+
+func (c comparison) build(b *builder) {
+  b.ident(c.field)
+  b.write(" ", c.operator, " ")
+  b.param(c.value)
+}
+
+// Eq holds when field equals value.
+func Eq(field string, value interface{}) Cond {
+  return comparison{field, "=", value}
+}
+
+// Ne holds when field differs from value.
+func Ne(field string, value interface{}) Cond {
+  return comparison{field, "<>", value}
+}
+
+// Lt holds when field is lower than value.
+func Lt(field string, value interface{}) Cond {
+  return comparison{field, "<", value}
+}
+
+// Lte holds when field is lower than or equal to value.
+func Lte(field string, value interface{}) Cond {
+  return comparison{field, "<=", value}
+}
+
+// Gt holds when field is greater than value.
+func Gt(field string, value interface{}) Cond {
+  return comparison{field, ">", value}
+}
+
+// Gte holds when field is greater than or equal to value.
+func Gte(field string, value interface{}) Cond {
+  return comparison{field, ">=", value}
+}
+
+// Like holds when field matches pattern, where % stands for any text.
+func Like(field string, pattern string) Cond {
+  return comparison{field, "like", pattern}
+}
+
+// Contains holds when the collection in field contains value.
+func Contains(field string, value interface{}) Cond {
+  return comparison{field, "contains", value}
+}
+
+type in struct {
+  field  string
+  values []interface{}
+}
+
+func (c in) build(b *builder) {
+  b.ident(c.field)
+  b.write(" in [")
+  for i, value := range c.values {
+    if i > 0 {
+      b.write(", ")
+    }
+    b.param(value)
+  }
+  b.write("]")
+}
+
+// In holds when field equals one of values.
+func In(field string, values ...interface{}) Cond {
+  return in{field, values}
+}
+
+type null struct {
+  field string
+  not   bool
+}
+
+func (c null) build(b *builder) {
+  b.ident(c.field)
+  if c.not {
+    b.write(" is not null")
+  } else {
+    b.write(" is null")
+  }
+}
+
+// IsNull holds when field is null or missing.
+func IsNull(field string) Cond {
+  return null{field, false}
+}
+
+// IsNotNull holds when field has a value.
+func IsNotNull(field string) Cond {
+  return null{field, true}
+}
+
+type junction struct {
+  operator string
+  conds    []Cond
+}
+
+func (c junction) build(b *builder) {
+  b.write("(")
+  for i, cond := range c.conds {
+    if i > 0 {
+      b.write(" ", c.operator, " ")
+    }
+    cond.build(b)
+  }
+  b.write(")")
+}
+
+// And holds when all of conds hold.
+func And(conds ...Cond) Cond {
+  return junction{"and", conds}
+}
+
+// Or holds when any of conds holds.
+func Or(conds ...Cond) Cond {
+  return junction{"or", conds}
+}
+
+type not struct {
+  cond Cond
+}
+
+func (c not) build(b *builder) {
+  b.write("not (")
+  c.cond.build(b)
+  b.write(")")
+}
+
+// Not holds when cond doesn't.
+func Not(cond Cond) Cond {
+  return not{cond}
+}
+
+type raw struct {
+  text   string
+  params []interface{}
+}
+
+func (c raw) build(b *builder) {
+  b.write(c.text)
+  b.params = append(b.params, c.params...)
+}
+
+// Raw is a condition written by hand, e.g. out('Referrer').size() > ?, with
+// a ? standing for every one of params.
+func Raw(text string, params ...interface{}) Cond {
+  return raw{text, params}
+}
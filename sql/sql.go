@@ -0,0 +1,151 @@
+// Package sql builds OrientDB SQL statements whose values are kept apart as
+// positional parameters instead of being quoted into the text:
+//
+//	sql.Select().From("Person").Where(sql.Eq("name", name)).Limit(10)
+//
+// renders select from Person where name = ? limit 10 along with [name]. Run
+// them with goog's DataBase.Exec.
+package sql
+
+import (
+  "errors"
+  "fmt"
+  "regexp"
+  "strings"
+)
+
+var (
+  // ErrInvalidIdentifier is returned when a class or field name can't be
+  // written unquoted in a statement.
+  ErrInvalidIdentifier = errors.New(`Invalid identifier`)
+
+  // ErrInvalidTarget is returned when a statement's target is neither a
+  // class, a record id nor a sub query.
+  ErrInvalidTarget = errors.New(`Invalid target`)
+)
+
+var (
+  // identifier matches class and field names, including record attributes
+  // such as @rid and dotted paths such as address.city.
+  identifier = regexp.MustCompile(`^[@$]?[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+  // rid matches a record id such as #11:1.
+  rid = regexp.MustCompile(`^#-?\d+:-?\d+$`)
+
+  // order matches an ORDER BY item: a field optionally followed by ASC or
+  // DESC.
+  order = regexp.MustCompile(`(?i)^(\S+)(\s+(asc|desc))?$`)
+)
+
+// Statement is implemented by every statement builder.
+type Statement interface {
+  // Build returns the statement's text, with a ? standing for every value,
+  // and the values in order.
+  Build() (string, []interface{}, error)
+}
+
+// builder accumulates a statement's text and parameters, keeping the first
+// error found.
+type builder struct {
+  text   strings.Builder
+  params []interface{}
+  err    error
+}
+
+func (b *builder) write(s ...string) {
+  for _, part := range s {
+    b.text.WriteString(part)
+  }
+}
+
+func (b *builder) param(value interface{}) {
+  b.text.WriteString("?")
+  b.params = append(b.params, value)
+}
+
+func (b *builder) ident(name string) {
+  if !identifier.MatchString(name) {
+    b.fail(fmt.Errorf("%w %q.", ErrInvalidIdentifier, name))
+    return
+  }
+
+  b.text.WriteString(name)
+}
+
+// target writes what a statement works on: a class name, a record id (given
+// as a string or as anything with a String method, such as goog.RID), a list
+// of record ids or a sub query.
+func (b *builder) target(target interface{}) {
+  switch t := target.(type) {
+  case *SelectStmt:
+    b.write("(")
+    t.build(b)
+    b.write(")")
+    return
+  case fmt.Stringer:
+    target = t.String()
+  case []string:
+    b.write("[")
+    for i, r := range t {
+      if i > 0 {
+        b.write(", ")
+      }
+      b.target(r)
+    }
+    b.write("]")
+    return
+  }
+
+  s, ok := target.(string)
+  switch {
+  case ok && rid.MatchString(s):
+    b.write(s)
+  case ok && identifier.MatchString(s):
+    b.write(s)
+  default:
+    b.fail(fmt.Errorf("%w %v.", ErrInvalidTarget, target))
+  }
+}
+
+func (b *builder) fail(err error) {
+  if b.err == nil {
+    b.err = err
+  }
+}
+
+func (b *builder) result() (string, []interface{}, error) {
+  if b.err != nil {
+    return "", nil, b.err
+  }
+
+  return b.text.String(), b.params, nil
+}
+
+// assignment is a field = value pair of a SET clause.
+type assignment struct {
+  field string
+  value interface{}
+}
+
+func buildSet(b *builder, set []assignment) {
+  if len(set) == 0 {
+    return
+  }
+
+  b.write(" set ")
+  for i, a := range set {
+    if i > 0 {
+      b.write(", ")
+    }
+    b.ident(a.field)
+    b.write(" = ")
+    b.param(a.value)
+  }
+}
+
+func buildWhere(b *builder, where Cond) {
+  if where != nil {
+    b.write(" where ")
+    where.build(b)
+  }
+}
@@ -0,0 +1,83 @@
+package sql
+
+import (
+  "errors"
+  "reflect"
+  "testing"
+)
+
+type testRID string
+
+func (r testRID) String() string {
+  return string(r)
+}
+
+func TestStatements(t *testing.T) {
+  tests := []struct {
+    stmt   Statement
+    text   string
+    params []interface{}
+  }{
+    {
+      Select().From("Person").Where(Eq("name", "Misa' or '1' = '1")).OrderBy("name", "age DESC").Skip(5).Limit(10),
+      "select from Person where name = ? order by name, age desc skip 5 limit 10",
+      []interface{}{"Misa' or '1' = '1"},
+    },
+    {
+      Select("name", "out('Referrer').size() as referrals").From(Select().From("Person").Where(Gt("age", 30))).
+        Where(And(Like("name", "M%"), Or(IsNull("nick"), In("nick", "a", "b")), Not(Lte("referrals", 2)))),
+      "select name, out('Referrer').size() as referrals from (select from Person where age > ?) where (name like ? and (nick is null or nick in [?, ?]) and not (referrals <= ?))",
+      []interface{}{30, "M%", "a", "b", 2},
+    },
+    {
+      Update(testRID("#11:1")).Set("name", "Nora").Set("age", 31).Where(Ne("name", "Nora")),
+      "update #11:1 set name = ?, age = ? where name <> ?",
+      []interface{}{"Nora", 31, "Nora"},
+    },
+    {
+      Insert("Person").Set("name", "Beto"),
+      "insert into Person set name = ?",
+      []interface{}{"Beto"},
+    },
+    {
+      Delete("Person").Where(Raw("out('Referrer').size() = ?", 0)).Limit(1),
+      "delete from Person where out('Referrer').size() = ? limit 1",
+      []interface{}{0},
+    },
+    {
+      CreateVertex("Person").Set("name", "Nor"),
+      "create vertex Person set name = ?",
+      []interface{}{"Nor"},
+    },
+    {
+      CreateEdge("Referrer").From(Select().From("Person").Where(Eq("name", "Misa"))).To([]string{"#11:1", "#11:2"}).Set("since", 2014),
+      "create edge Referrer from (select from Person where name = ?) to [#11:1, #11:2] set since = ?",
+      []interface{}{"Misa", 2014},
+    },
+  }
+
+  for _, test := range tests {
+    text, params, err := test.stmt.Build()
+    if err != nil {
+      t.Fatal(err)
+    }
+
+    if text != test.text || !reflect.DeepEqual(params, test.params) {
+      t.Fatalf("Expecting %q %v, got %q %v.", test.text, test.params, text, params)
+    }
+  }
+}
+
+func TestInvalidStatements(t *testing.T) {
+  if _, _, err := Select().From("Person; drop class Person").Build(); !errors.Is(err, ErrInvalidTarget) {
+    t.Fatalf("Expecting ErrInvalidTarget, got %v.", err)
+  }
+
+  if _, _, err := Select().From("Person").Where(Eq("name = 'x' or name", "y")).Build(); !errors.Is(err, ErrInvalidIdentifier) {
+    t.Fatalf("Expecting ErrInvalidIdentifier, got %v.", err)
+  }
+
+  if _, _, err := Select().From("Person").OrderBy("name; delete").Build(); !errors.Is(err, ErrInvalidIdentifier) {
+    t.Fatalf("Expecting ErrInvalidIdentifier, got %v.", err)
+  }
+}
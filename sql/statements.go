@@ -0,0 +1,302 @@
+package sql
+
+import (
+  "fmt"
+  "strconv"
+  "strings"
+)
+
+// SelectStmt builds a SELECT statement.
+type SelectStmt struct {
+  projections []string
+  target      interface{}
+  where       Cond
+  orderBy     []string
+  skip        int
+  limit       int
+}
+
+// Select starts a SELECT statement returning the given projections, or whole
+// records when there are none.
+func Select(projections ...string) *SelectStmt {
+  return &SelectStmt{projections: projections}
+}
+
+// From sets what to select from: a class name, a record id, a list of record
+// ids or another *SelectStmt.
+func (s *SelectStmt) From(target interface{}) *SelectStmt {
+  s.target = target
+  return s
+}
+
+// Where filters the records selected.
+func (s *SelectStmt) Where(cond Cond) *SelectStmt {
+  s.where = cond
+  return s
+}
+
+// OrderBy sorts the records selected by the given fields, each optionally
+// followed by asc or desc.
+func (s *SelectStmt) OrderBy(fields ...string) *SelectStmt {
+  s.orderBy = fields
+  return s
+}
+
+// Skip skips the first n records.
+func (s *SelectStmt) Skip(n int) *SelectStmt {
+  s.skip = n
+  return s
+}
+
+// Limit returns at most n records.
+func (s *SelectStmt) Limit(n int) *SelectStmt {
+  s.limit = n
+  return s
+}
+
+// Build implements Statement.
+func (s *SelectStmt) Build() (string, []interface{}, error) {
+  b := &builder{}
+  s.build(b)
+  return b.result()
+}
+
+func (s *SelectStmt) build(b *builder) {
+  b.write("select ")
+
+  // Projections are expressions (count(*), out('Referrer').name...) rather
+  // than plain fields, so they are written as given; values must still go
+  // through Where.
+  if len(s.projections) > 0 {
+    b.write(strings.Join(s.projections, ", "), " ")
+  }
+
+  b.write("from ")
+  b.target(s.target)
+  buildWhere(b, s.where)
+
+  if len(s.orderBy) > 0 {
+    b.write(" order by ")
+    for i, field := range s.orderBy {
+      if i > 0 {
+        b.write(", ")
+      }
+      m := order.FindStringSubmatch(strings.TrimSpace(field))
+      if m == nil {
+        b.fail(fmt.Errorf("%w %q.", ErrInvalidIdentifier, field))
+        return
+      }
+      b.ident(m[1])
+      if m[3] != "" {
+        b.write(" ", strings.ToLower(m[3]))
+      }
+    }
+  }
+
+  if s.skip > 0 {
+    b.write(" skip ", strconv.Itoa(s.skip))
+  }
+
+  if s.limit > 0 {
+    b.write(" limit ", strconv.Itoa(s.limit))
+  }
+}
+
+// UpdateStmt builds an UPDATE statement.
+type UpdateStmt struct {
+  target interface{}
+  set    []assignment
+  where  Cond
+  limit  int
+}
+
+// Update starts an UPDATE statement over a class, a record id or a list of
+// record ids.
+func Update(target interface{}) *UpdateStmt {
+  return &UpdateStmt{target: target}
+}
+
+// Set sets field to value.
+func (s *UpdateStmt) Set(field string, value interface{}) *UpdateStmt {
+  s.set = append(s.set, assignment{field, value})
+  return s
+}
+
+// Where filters the records updated.
+func (s *UpdateStmt) Where(cond Cond) *UpdateStmt {
+  s.where = cond
+  return s
+}
+
+// Limit updates at most n records.
+func (s *UpdateStmt) Limit(n int) *UpdateStmt {
+  s.limit = n
+  return s
+}
+
+// Build implements Statement.
+func (s *UpdateStmt) Build() (string, []interface{}, error) {
+  b := &builder{}
+
+  b.write("update ")
+  b.target(s.target)
+  buildSet(b, s.set)
+  buildWhere(b, s.where)
+
+  if s.limit > 0 {
+    b.write(" limit ", strconv.Itoa(s.limit))
+  }
+
+  return b.result()
+}
+
+// InsertStmt builds an INSERT statement.
+type InsertStmt struct {
+  class string
+  set   []assignment
+}
+
+// Insert starts an INSERT statement into a class.
+func Insert(class string) *InsertStmt {
+  return &InsertStmt{class: class}
+}
+
+// Set sets field to value.
+func (s *InsertStmt) Set(field string, value interface{}) *InsertStmt {
+  s.set = append(s.set, assignment{field, value})
+  return s
+}
+
+// Build implements Statement.
+func (s *InsertStmt) Build() (string, []interface{}, error) {
+  b := &builder{}
+
+  b.write("insert into ")
+  b.ident(s.class)
+  buildSet(b, s.set)
+
+  return b.result()
+}
+
+// DeleteStmt builds a DELETE statement.
+type DeleteStmt struct {
+  class string
+  where Cond
+  limit int
+}
+
+// Delete starts a DELETE statement over a class. Use DELETE VERTEX (through
+// Raw SQL or goog's Graph) to remove vertices along with their edges.
+func Delete(class string) *DeleteStmt {
+  return &DeleteStmt{class: class}
+}
+
+// Where filters the records deleted.
+func (s *DeleteStmt) Where(cond Cond) *DeleteStmt {
+  s.where = cond
+  return s
+}
+
+// Limit deletes at most n records.
+func (s *DeleteStmt) Limit(n int) *DeleteStmt {
+  s.limit = n
+  return s
+}
+
+// Build implements Statement.
+func (s *DeleteStmt) Build() (string, []interface{}, error) {
+  b := &builder{}
+
+  b.write("delete from ")
+  b.ident(s.class)
+  buildWhere(b, s.where)
+
+  if s.limit > 0 {
+    b.write(" limit ", strconv.Itoa(s.limit))
+  }
+
+  return b.result()
+}
+
+// CreateVertexStmt builds a CREATE VERTEX statement.
+type CreateVertexStmt struct {
+  class string
+  set   []assignment
+}
+
+// CreateVertex starts a CREATE VERTEX statement for a class, V when empty.
+func CreateVertex(class string) *CreateVertexStmt {
+  if class == "" {
+    class = "V"
+  }
+
+  return &CreateVertexStmt{class: class}
+}
+
+// Set sets field to value.
+func (s *CreateVertexStmt) Set(field string, value interface{}) *CreateVertexStmt {
+  s.set = append(s.set, assignment{field, value})
+  return s
+}
+
+// Build implements Statement.
+func (s *CreateVertexStmt) Build() (string, []interface{}, error) {
+  b := &builder{}
+
+  b.write("create vertex ")
+  b.ident(s.class)
+  buildSet(b, s.set)
+
+  return b.result()
+}
+
+// CreateEdgeStmt builds a CREATE EDGE statement.
+type CreateEdgeStmt struct {
+  class    string
+  from, to interface{}
+  set      []assignment
+}
+
+// CreateEdge starts a CREATE EDGE statement for a class, E when empty.
+func CreateEdge(class string) *CreateEdgeStmt {
+  if class == "" {
+    class = "E"
+  }
+
+  return &CreateEdgeStmt{class: class}
+}
+
+// From sets the vertices the edges go from: a record id, a list of record ids
+// or a *SelectStmt.
+func (s *CreateEdgeStmt) From(target interface{}) *CreateEdgeStmt {
+  s.from = target
+  return s
+}
+
+// To sets the vertices the edges go to: a record id, a list of record ids or
+// a *SelectStmt.
+func (s *CreateEdgeStmt) To(target interface{}) *CreateEdgeStmt {
+  s.to = target
+  return s
+}
+
+// Set sets field to value.
+func (s *CreateEdgeStmt) Set(field string, value interface{}) *CreateEdgeStmt {
+  s.set = append(s.set, assignment{field, value})
+  return s
+}
+
+// Build implements Statement.
+func (s *CreateEdgeStmt) Build() (string, []interface{}, error) {
+  b := &builder{}
+
+  b.write("create edge ")
+  b.ident(s.class)
+  b.write(" from ")
+  b.target(s.from)
+  b.write(" to ")
+  b.target(s.to)
+  buildSet(b, s.set)
+
+  return b.result()
+}
@@ -0,0 +1,184 @@
+package goog
+
+import (
+  "context"
+  "regexp"
+  "strconv"
+  "strings"
+
+  l4g "code.google.com/p/log4go"
+)
+
+// Strategy is the order in which a traversal visits records.
+type Strategy string
+
+const (
+  DEPTH_FIRST   Strategy = "DEPTH_FIRST"
+  BREADTH_FIRST Strategy = "BREADTH_FIRST"
+)
+
+// pathRIDs finds the record ids in a $path such as (#11:0).out[0](#11:1).
+var pathRIDs = regexp.MustCompile(`#-?\d+:-?\d+`)
+
+// Traversal builds and runs an OrientDB TRAVERSE statement:
+//
+//	db.Traversal().From(misa).Fields("out_Referrer", "in").MaxDepth(2).Run()
+//
+// compiles to
+//
+//	traverse out_Referrer, in from #11:0 maxdepth 2
+type Traversal struct {
+  db       *DataBase
+  fields   []string
+  target   string
+  maxDepth int
+  while    string
+  limit    int
+  strategy Strategy
+}
+
+// TraversalResult is a record reached by a traversal, along with the depth at
+// which it was found and the path followed to reach it.
+type TraversalResult struct {
+  *Document
+  Depth int
+  Path  string
+}
+
+// Traversal starts building a traversal over the database.
+func (db *DataBase) Traversal() *Traversal {
+  return &Traversal{db: db, maxDepth: -1}
+}
+
+// From starts the traversal at the given records.
+func (t *Traversal) From(rids ...RID) *Traversal {
+  targets := make([]string, len(rids))
+  for i, rid := range rids {
+    targets[i] = rid.String()
+  }
+
+  t.target = strings.Join(targets, ", ")
+  if len(rids) > 1 {
+    t.target = "[" + t.target + "]"
+  }
+
+  return t
+}
+
+// FromQuery starts the traversal at the records returned by a sub query, e.g.
+// select from Person where name = 'Nor'.
+func (t *Traversal) FromQuery(sql string) *Traversal {
+  t.target = "(" + sql + ")"
+  return t
+}
+
+// Fields sets the fields (or functions such as out() and any()) followed from
+// every record. All of them (*) are followed by default.
+func (t *Traversal) Fields(fields ...string) *Traversal {
+  t.fields = fields
+  return t
+}
+
+// MaxDepth stops the traversal at the given depth, the starting records being
+// at depth 0.
+func (t *Traversal) MaxDepth(depth int) *Traversal {
+  t.maxDepth = depth
+  return t
+}
+
+// While keeps traversing as long as the predicate holds, e.g. $depth <= 2 or
+// @class = 'Person'.
+func (t *Traversal) While(predicate string) *Traversal {
+  t.while = predicate
+  return t
+}
+
+// Strategy sets the order in which records are visited, DEPTH_FIRST by
+// default.
+func (t *Traversal) Strategy(strategy Strategy) *Traversal {
+  t.strategy = strategy
+  return t
+}
+
+// Limit stops the traversal after the given number of records.
+func (t *Traversal) Limit(limit int) *Traversal {
+  t.limit = limit
+  return t
+}
+
+// SQL returns the TRAVERSE statement the traversal compiles to. OrientDB
+// doesn't accept MAXDEPTH along with WHILE, so a maximum depth is folded into
+// the predicate when both are set.
+func (t *Traversal) SQL() string {
+  fields := "*"
+  if len(t.fields) > 0 {
+    fields = strings.Join(t.fields, ", ")
+  }
+
+  sql := "traverse " + fields + " from " + t.target
+
+  switch {
+  case t.while != "" && t.maxDepth >= 0:
+    sql += " while $depth <= " + strconv.Itoa(t.maxDepth) + " and (" + t.while + ")"
+  case t.while != "":
+    sql += " while " + t.while
+  case t.maxDepth >= 0:
+    sql += " maxdepth " + strconv.Itoa(t.maxDepth)
+  }
+
+  if t.limit > 0 {
+    sql += " limit " + strconv.Itoa(t.limit)
+  }
+
+  if t.strategy != "" {
+    sql += " strategy " + string(t.strategy)
+  }
+
+  return sql
+}
+
+// Run executes the traversal and returns the records visited, in order.
+func (t *Traversal) Run() ([]TraversalResult, error) {
+  l4g.Trace("Inside Traversal.Run")
+
+  return t.RunContext(context.Background())
+}
+
+// RunContext is like Run, the request being canceled when ctx is done.
+func (t *Traversal) RunContext(ctx context.Context) ([]TraversalResult, error) {
+  l4g.Trace("Inside Traversal.RunContext")
+
+  if t.target == "" {
+    return nil, ErrNoTarget
+  }
+
+  docs, err := t.db.QueryContext(ctx, "select *, $depth, $path from ("+t.SQL()+")", 0, "")
+  if err != nil {
+    return nil, err
+  }
+
+  return traversalResults(docs), nil
+}
+
+// traversalResults takes $depth and $path out of the projected fields. The
+// projection gives every record a temporary id, so the real one is read from
+// the end of its path.
+func traversalResults(docs []*Document) []TraversalResult {
+  results := make([]TraversalResult, len(docs))
+
+  for i, doc := range docs {
+    results[i] = TraversalResult{
+      Document: doc,
+      Depth:    int(doc.GetInt64("$depth")),
+      Path:     doc.GetString("$path"),
+    }
+    delete(doc.Fields, "$depth")
+    delete(doc.Fields, "$path")
+
+    if rids := pathRIDs.FindAllString(results[i].Path, -1); len(rids) > 0 && doc.RID.IsTemporary() {
+      doc.RID, _ = ParseRID(rids[len(rids)-1])
+    }
+  }
+
+  return results
+}
@@ -0,0 +1,56 @@
+package goog
+
+import (
+  "net/http"
+  "testing"
+)
+
+func TestTraversalSQL(t *testing.T) {
+  db := &DataBase{}
+
+  sql := db.Traversal().FromQuery("select from Person where name = 'Nor'").MaxDepth(2).SQL()
+  if sql != "traverse * from (select from Person where name = 'Nor') maxdepth 2" {
+    t.Fatalf("Unexpected statement %q.", sql)
+  }
+
+  sql = db.Traversal().From(RID{11, 0}, RID{11, 1}).Fields("out", "in_Referrer").
+    MaxDepth(3).While("@class = 'Person'").Strategy(BREADTH_FIRST).Limit(10).SQL()
+  if sql != "traverse out, in_Referrer from [#11:0, #11:1] while $depth <= 3 and (@class = 'Person') limit 10 strategy BREADTH_FIRST" {
+    t.Fatalf("Unexpected statement %q.", sql)
+  }
+}
+
+func TestTraversalRun(t *testing.T) {
+  var path string
+
+  ts, db := connectTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+    path = r.URL.Path
+    w.Header().Set("Content-Type", "application/json; charset=utf-8")
+    w.Write([]byte(`{"result": [
+      {"@type": "d", "@rid": "#-2:1", "@version": 0, "name": "Nor", "$depth": 0, "$path": "(#11:2)"},
+      {"@type": "d", "@rid": "#-2:2", "@version": 0, "name": "Beto", "$depth": 2, "$path": "(#11:2).in_Referrer[0](#12:1).out(#11:1)"}
+    ]}`))
+  })
+  defer ts.Close()
+
+  if _, err := db.Traversal().Run(); err != ErrNoTarget {
+    t.Fatalf("Expecting ErrNoTarget, got %v.", err)
+  }
+
+  results, err := db.Traversal().From(RID{11, 2}).While("$depth <= 2").Run()
+  if err != nil {
+    t.Fatal(err)
+  }
+
+  if path != "/query/geneology/sql/select *, $depth, $path from (traverse * from #11:2 while $depth <= 2)/-1" {
+    t.Fatalf("Unexpected path %q.", path)
+  }
+
+  if len(results) != 2 || results[1].Depth != 2 || results[1].RID != (RID{11, 1}) || results[1].GetString("name") != "Beto" {
+    t.Fatalf("Unexpected results %v.", results)
+  }
+
+  if _, ok := results[1].Fields["$depth"]; ok {
+    t.Fatalf("$depth should not be left among the fields.")
+  }
+}